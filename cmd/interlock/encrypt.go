@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/ehazlett/interlock/ext/lb/secrets"
+)
+
+var cmdEncrypt = cli.Command{
+	Name:   "encrypt",
+	Usage:  "encrypt a secret from stdin for use in a file secret store",
+	Action: encryptAction,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "key, k",
+			Usage: "path to encryption key file",
+			Value: "",
+		},
+	},
+}
+
+func encryptAction(c *cli.Context) {
+	keyPath := c.String("key")
+	if keyPath == "" {
+		log.Fatal("you must specify a key file")
+	}
+
+	key, err := secrets.ReadKey(keyPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	enc, err := secrets.Encrypt(key, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := os.Stdout.Write(enc); err != nil {
+		log.Fatal(err)
+	}
+}
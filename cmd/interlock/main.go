@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	log "github.com/Sirupsen/logrus"
@@ -20,16 +21,30 @@ func main() {
 			Name:  "debug, D",
 			Usage: "Enable debug logging",
 		},
+		cli.StringFlag{
+			Name:  "log-format",
+			Usage: "Log format (text, json)",
+			Value: "text",
+		},
 	}
 	app.Commands = []cli.Command{
 		cmdSpec,
 		cmdRun,
+		cmdEncrypt,
 	}
 	app.Before = func(c *cli.Context) error {
 		if c.Bool("debug") {
 			log.SetLevel(log.DebugLevel)
 		}
 
+		switch c.String("log-format") {
+		case "text":
+		case "json":
+			log.SetFormatter(&log.JSONFormatter{})
+		default:
+			return fmt.Errorf("unknown log format: %s", c.String("log-format"))
+		}
+
 		return nil
 	}
 
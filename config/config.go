@@ -1,56 +1,115 @@
 package config
 
-// TODO: Rules allow for filtering metrics
+// Rule selects the containers monitored by the beacon extension.  All
+// matchers of a rule must match the container.
 // Note: for use with the Beacon extension only
 type Rule struct {
-	Type  string
-	Regex string
+	Name          string   // rule name
+	Action        string   // include, exclude
+	Labels        []string // label key or key=value regex
+	ContainerName string   // container name regex
+	Image         string   // image regex
+	Network       string   // network name regex
+}
+
+// Alert is a beacon alert evaluated for the monitored containers on each
+// stats interval
+// Note: for use with the Beacon extension only
+type Alert struct {
+	Name string // alert name
+	Expr string // <metric> <op> <threshold> or <metric> increasing
+	For  string // duration the condition must hold before the alert fires
+	Rule string // containers matched by the rule (all monitored containers if empty)
+}
+
+// Route is a static route that does not require an upstream container
+// Type is one of redirect, www or static
+type Route struct {
+	Type         string // redirect, www, static
+	Domain       string // domain to match
+	Target       string // redirect target (i.e. https://example.com)
+	Code         int    // response code
+	Body         string // static response body
+	PreservePath bool   // append the request path to the redirect target
+}
+
+// Notifier sends interlock events (i.e. reloads and failures) to a webhook
+type Notifier struct {
+	Name          string   // notifier name (used in logs)
+	Type          string   // webhook, slack
+	URL           string   // webhook url
+	Secret        string   // webhook (hmac-sha256 signature key)
+	Events        []string // events to send (all if empty)
+	MaxRetries    int      // delivery attempts after the first failure
+	RetryInterval string   // initial retry interval (doubled on each retry)
+	RateLimit     int      // notifications per minute (unlimited if 0)
 }
 
 // ExtensionConfig has all options for all load balancer extensions
 // the extension itself will use whichever options needed
 type ExtensionConfig struct {
-	Name                          string           // extension name
-	ConfigPath                    string           // config file path
-	ConfigBasePath                string           `toml:"-"` // internal
-	PidPath                       string           // haproxy, nginx
-	TemplatePath                  string           // template file path
-	BackendOverrideAddress        string           // haproxy, nginx
-	ConnectTimeout                int              // haproxy
-	ServerTimeout                 int              // haproxy
-	ClientTimeout                 int              // haproxy
-	MaxConn                       int              // haproxy, nginx
-	Port                          int              // haproxy, nginx
-	SyslogAddr                    string           // haproxy
-	AdminUser                     string           // haproxy
-	AdminPass                     string           // haproxy
-	SSLCertPath                   string           // haproxy, nginx
-	SSLCert                       string           // haproxy
-	SSLPort                       int              // haproxy, nginx
-	SSLOpts                       string           // haproxy
-	SSLDefaultDHParam             int              // haproxy
-	SSLServerVerify               string           // haproxy
-	DHParam                       bool             // nginx
-	DHParamPath                   string           // nginx
-	NginxPlusEnabled              bool             // nginx
-	User                          string           // nginx
-	WorkerProcesses               int              // nginx
-	RLimitNoFile                  int              // nginx
-	ProxyConnectTimeout           int              // nginx
-	ProxySendTimeout              int              // nginx
-	ProxyReadTimeout              int              // nginx
-	SendTimeout                   int              // nginx
-	SSLCiphers                    string           // nginx
-	SSLProtocols                  string           // nginx
-	StatsInterval                 string           // beacon
-	StatsBackendType              string           // beacon (influxdb, prometheus)
-	StatsPrometheusPushGatewayURL string           // beacon (prometheus)
-	StatsInfluxDBAddress          string           // beacon (influxdb)
-	StatsInfluxDBUser             string           // beacon (influxdb user)
-	StatsInfluxDBPassword         string           // beacon (influxdb password)
-	StatsInfluxDBDatabase         string           // beacon (influxdb)
-	StatsInfluxDBPrecision        string           // beacon (influxdb)
-	Rules                         map[string]*Rule // beacon FIXME: this isn't loaded properly from toml; we set it as a hack now
+	Name                          string   // extension name
+	ConfigPath                    string   // config file path
+	ConfigBasePath                string   `toml:"-"` // internal
+	PidPath                       string   // haproxy, nginx
+	TemplatePath                  string   // template file path
+	BackendOverrideAddress        string   // haproxy, nginx
+	ConnectTimeout                int      // haproxy
+	ServerTimeout                 int      // haproxy
+	ClientTimeout                 int      // haproxy
+	MaxConn                       int      // haproxy, nginx
+	Port                          int      // haproxy, nginx
+	SyslogAddr                    string   // haproxy
+	AdminUser                     string   // haproxy
+	AdminPass                     string   // haproxy
+	SSLCertPath                   string   // haproxy, nginx
+	SSLCert                       string   // haproxy
+	SSLPort                       int      // haproxy, nginx
+	SSLOpts                       string   // haproxy
+	SSLDefaultDHParam             int      // haproxy
+	SSLServerVerify               string   // haproxy
+	DHParam                       bool     // nginx
+	DHParamPath                   string   // nginx
+	NginxPlusEnabled              bool     // nginx
+	User                          string   // nginx
+	WorkerProcesses               int      // nginx
+	RLimitNoFile                  int      // nginx
+	ProxyConnectTimeout           int      // nginx
+	ProxySendTimeout              int      // nginx
+	ProxyReadTimeout              int      // nginx
+	SendTimeout                   int      // nginx
+	SSLCiphers                    string   // nginx
+	SSLProtocols                  string   // nginx
+	Routes                        []*Route // haproxy, nginx
+	ErrorPagesPath                string   // haproxy, nginx
+	BasicAuthPath                 string   // haproxy, nginx (htpasswd files for interlock.basic_auth)
+	UpstreamProbeInterval         string   // haproxy, nginx (disabled if empty)
+	UpstreamProbeTimeout          string   // haproxy, nginx
+	RequireHealthy                bool     // haproxy, nginx (docker healthcheck)
+	RuntimeAPIPort                int      // haproxy (stats socket), nginx (plus api)
+	RuntimeAPIClients             []string `toml:"-"` // internal (interlock addresses allowed to use the runtime api)
+	DrainTimeout                  string   // haproxy, nginx
+	ServerSlots                   int      // haproxy (runtime api)
+	ProxyStatsInterval            string   // haproxy, nginx (disabled if empty)
+	DiffWebhook                   string   // haproxy, nginx
+	AccessLogAddr                 string   // haproxy, nginx (syslog udp listen addr; disabled if empty)
+	AccessLogEndpoint             string   // haproxy, nginx (syslog addr used by the proxies)
+	AccessLogForward              string   // haproxy, nginx (file://, tcp://)
+	SSLCertExpiryWarning          int      // haproxy, nginx (days)
+	SecretStoreAddr               string   // haproxy, nginx (consul://, etcd://, file://)
+	SecretStoreKeyPath            string   // haproxy, nginx (file)
+	SecretStorePollInterval       string   // haproxy, nginx
+	StatsInterval                 string   // beacon
+	StatsBackendType              string   // beacon (influxdb, prometheus)
+	StatsPrometheusPushGatewayURL string   // beacon (prometheus)
+	StatsInfluxDBAddress          string   // beacon (influxdb)
+	StatsInfluxDBUser             string   // beacon (influxdb user)
+	StatsInfluxDBPassword         string   // beacon (influxdb password)
+	StatsInfluxDBDatabase         string   // beacon (influxdb)
+	StatsInfluxDBPrecision        string   // beacon (influxdb)
+	Rules                         []*Rule  // beacon
+	Alerts                        []*Alert // beacon
+	AlertLogPath                  string   // beacon (json lines)
 }
 
 // Config is the top level configuration
@@ -62,7 +121,10 @@ type Config struct {
 	TLSKey        string
 	AllowInsecure bool
 	EnableMetrics bool
+	EnableAPI     bool
 	PollInterval  string
 	Extensions    []*ExtensionConfig
-	Rules         map[string]*Rule // beacon TODO: move to ExtensionConfig
+	Notifiers     []*Notifier
+	TraceExporter string // otlp, file (disabled if empty)
+	TraceEndpoint string // otlp (http url), file (path)
 }
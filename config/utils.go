@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+
 	"github.com/BurntSushi/toml"
 	log "github.com/Sirupsen/logrus"
 )
@@ -8,23 +10,49 @@ import (
 // ParseConfig returns a Config object from a raw string config TOML
 func ParseConfig(data string) (*Config, error) {
 	var cfg Config
-	if _, err := toml.Decode(data, &cfg); err != nil {
+	md, err := toml.Decode(data, &cfg)
+	if err != nil {
 		return nil, err
 	}
 
+	// beacon rules used to be configured at the top level
+	if md.IsDefined("Rules") {
+		return nil, fmt.Errorf("top level Rules are no longer supported; configure them as [[Extensions.Rules]] of the beacon extension (see docs/extensions/beacon.md)")
+	}
+
 	for _, ext := range cfg.Extensions {
 		// setup defaults for missing config entries
 		if err := SetConfigDefaults(ext); err != nil {
 			return nil, err
 		}
+	}
 
-		// FIXME: toml isn't being parse right so we hack the rules in like so
-		ext.Rules = cfg.Rules
+	for _, n := range cfg.Notifiers {
+		SetNotifierDefaults(n)
 	}
 
 	return &cfg, nil
 }
 
+// SetNotifierDefaults sets default values if not present
+func SetNotifierDefaults(n *Notifier) {
+	if n.Type == "" {
+		n.Type = "webhook"
+	}
+
+	if n.Name == "" {
+		n.Name = n.Type
+	}
+
+	if n.MaxRetries == 0 {
+		n.MaxRetries = 3
+	}
+
+	if n.RetryInterval == "" {
+		n.RetryInterval = "1s"
+	}
+}
+
 // SetConfigDefaults sets default values if not present
 // ExtensionConfig.Name must be set before calling this function
 func SetConfigDefaults(c *ExtensionConfig) error {
@@ -36,6 +64,10 @@ func SetConfigDefaults(c *ExtensionConfig) error {
 		c.Port = 80
 	}
 
+	if c.SSLCertExpiryWarning == 0 {
+		c.SSLCertExpiryWarning = 30
+	}
+
 	switch c.Name {
 	case "haproxy":
 		SetHAProxyConfigDefaults(c)
@@ -78,6 +110,18 @@ func SetHAProxyConfigDefaults(c *ExtensionConfig) {
 	if c.SSLServerVerify == "" {
 		c.SSLServerVerify = "required"
 	}
+
+	if c.SecretStorePollInterval == "" {
+		c.SecretStorePollInterval = "30s"
+	}
+
+	if c.UpstreamProbeTimeout == "" {
+		c.UpstreamProbeTimeout = "2s"
+	}
+
+	if c.DrainTimeout == "" {
+		c.DrainTimeout = "30s"
+	}
 }
 
 func SetNginxConfigDefaults(c *ExtensionConfig) {
@@ -116,6 +160,18 @@ func SetNginxConfigDefaults(c *ExtensionConfig) {
 	if c.SSLProtocols == "" {
 		c.SSLProtocols = "SSLv3 TLSv1 TLSv1.1 TLSv1.2"
 	}
+
+	if c.SecretStorePollInterval == "" {
+		c.SecretStorePollInterval = "30s"
+	}
+
+	if c.UpstreamProbeTimeout == "" {
+		c.UpstreamProbeTimeout = "2s"
+	}
+
+	if c.DrainTimeout == "" {
+		c.DrainTimeout = "30s"
+	}
 }
 
 func SetBeaconConfigDefaults(c *ExtensionConfig) {
@@ -126,4 +182,20 @@ func SetBeaconConfigDefaults(c *ExtensionConfig) {
 	if c.StatsInfluxDBPrecision == "" {
 		c.StatsInfluxDBPrecision = "s"
 	}
+
+	for i, r := range c.Rules {
+		if r.Action == "" {
+			r.Action = "include"
+		}
+
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule%d", i)
+		}
+	}
+
+	for i, a := range c.Alerts {
+		if a.Name == "" {
+			a.Name = fmt.Sprintf("alert%d", i)
+		}
+	}
 }
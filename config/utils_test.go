@@ -127,3 +127,128 @@ func TestSetHAProxyConfigDefaults(t *testing.T) {
 		t.Fatalf("expected default SSL server verify of required; received %d", cfg.SSLServerVerify)
 	}
 }
+
+func TestParseConfigNotifiers(t *testing.T) {
+	data := sampleConfig + `
+[[Notifiers]]
+URL = "http://127.0.0.1:9000/hook"
+Secret = "s3cr3t"
+
+[[Notifiers]]
+Name = "ops"
+Type = "slack"
+URL = "https://hooks.slack.com/services/x"
+Events = ["reload_failed"]
+RateLimit = 10
+`
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("error parsing config: %s", err)
+	}
+
+	if len(cfg.Notifiers) != 2 {
+		t.Fatalf("expected 2 notifiers; received %d", len(cfg.Notifiers))
+	}
+
+	n := cfg.Notifiers[0]
+	if n.Type != "webhook" || n.Name != "webhook" {
+		t.Fatalf("expected default webhook notifier; received type=%s name=%s", n.Type, n.Name)
+	}
+
+	if n.MaxRetries != 3 || n.RetryInterval != "1s" {
+		t.Fatalf("expected default retries; received retries=%d interval=%s", n.MaxRetries, n.RetryInterval)
+	}
+
+	n = cfg.Notifiers[1]
+	if n.Name != "ops" || n.Type != "slack" || n.RateLimit != 10 {
+		t.Fatalf("expected slack notifier ops; received %+v", n)
+	}
+
+	if len(n.Events) != 1 || n.Events[0] != "reload_failed" {
+		t.Fatalf("expected reload_failed event; received %v", n.Events)
+	}
+}
+
+func TestParseConfigRules(t *testing.T) {
+	data := sampleConfig + `
+[[Extensions]]
+Name = "beacon"
+
+[[Extensions.Rules]]
+Name = "web"
+Labels = ["com.example.team=web"]
+Image = "^nginx"
+
+[[Extensions.Rules]]
+Action = "exclude"
+ContainerName = "^interlock"
+`
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("error parsing config: %s", err)
+	}
+
+	rules := cfg.Extensions[0].Rules
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules; received %d", len(rules))
+	}
+
+	r := rules[0]
+	if r.Name != "web" || r.Action != "include" || r.Image != "^nginx" || len(r.Labels) != 1 {
+		t.Fatalf("unexpected rule: %+v", r)
+	}
+
+	r = rules[1]
+	if r.Name != "rule1" || r.Action != "exclude" || r.ContainerName != "^interlock" {
+		t.Fatalf("unexpected rule: %+v", r)
+	}
+}
+
+func TestParseConfigAlerts(t *testing.T) {
+	data := sampleConfig + `
+[[Extensions]]
+Name = "beacon"
+AlertLogPath = "/var/log/interlock/alerts.json"
+
+[[Extensions.Alerts]]
+Name = "memory"
+Expr = "memory_usage_percent > 90"
+For = "5m"
+
+[[Extensions.Alerts]]
+Expr = "network_rx_errors increasing"
+Rule = "web"
+`
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("error parsing config: %s", err)
+	}
+
+	alerts := cfg.Extensions[0].Alerts
+	if len(alerts) != 2 {
+		t.Fatalf("expected 2 alerts; received %d", len(alerts))
+	}
+
+	if a := alerts[0]; a.Name != "memory" || a.For != "5m" {
+		t.Fatalf("unexpected alert: %+v", a)
+	}
+
+	if a := alerts[1]; a.Name != "alert1" || a.Rule != "web" {
+		t.Fatalf("unexpected alert: %+v", a)
+	}
+}
+
+func TestParseConfigLegacyRules(t *testing.T) {
+	data := sampleConfig + `
+[Rules.web]
+Type = "image"
+Regex = "^nginx"
+`
+
+	if _, err := ParseConfig(data); err == nil {
+		t.Fatal("expected error for top level rules")
+	}
+}
@@ -0,0 +1,31 @@
+package ext
+
+import (
+	"time"
+)
+
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// Alert is a state change of an alert for a container
+type Alert struct {
+	Name          string    `json:"name"`
+	Ext           string    `json:"ext"`
+	State         string    `json:"state"`
+	Expr          string    `json:"expr"`
+	Rule          string    `json:"rule,omitempty"`
+	Container     string    `json:"container"`
+	ContainerName string    `json:"container_name"`
+	Value         float64   `json:"value"`
+	Since         time.Time `json:"since"` // time the condition started to hold
+	Time          time.Time `json:"time"`
+}
+
+// AlertExtension is implemented by extensions that evaluate alerts
+type AlertExtension interface {
+	Extension
+	// SetAlertHandler sets the func called when an alert fires or resolves
+	SetAlertHandler(fn func(a *Alert))
+}
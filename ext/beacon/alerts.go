@@ -0,0 +1,318 @@
+package beacon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext"
+)
+
+const (
+	opIncreasing = "increasing"
+)
+
+// alertMetrics are the container metrics available to alert expressions
+var alertMetrics = map[string]func(s *types.StatsJSON) float64{
+	"cpu_usage_percent":    cpuPercent,
+	"memory_usage_bytes":   func(s *types.StatsJSON) float64 { return float64(s.MemoryStats.Usage) },
+	"memory_usage_percent": memoryPercent,
+	"network_rx_errors":    networkTotal(func(n types.NetworkStats) uint64 { return n.RxErrors }),
+	"network_tx_errors":    networkTotal(func(n types.NetworkStats) uint64 { return n.TxErrors }),
+	"network_rx_dropped":   networkTotal(func(n types.NetworkStats) uint64 { return n.RxDropped }),
+	"network_tx_dropped":   networkTotal(func(n types.NetworkStats) uint64 { return n.TxDropped }),
+}
+
+// alertRule is a parsed config alert
+type alertRule struct {
+	name        string
+	expr        string
+	metric      string
+	op          string
+	threshold   float64
+	forDuration time.Duration
+	rule        string
+}
+
+// alertState is the state of an alert for a container
+type alertState struct {
+	rule    string // include rule matched by the container
+	value   float64
+	sampled bool
+	since   time.Time // zero if the condition does not hold
+	firing  bool
+}
+
+// alertEvaluator evaluates the alerts on the container stats and sends
+// an alert when it fires or resolves
+type alertEvaluator struct {
+	rules    []*alertRule
+	lock     *sync.Mutex
+	states   map[string]map[string]*alertState // container -> alert -> state
+	names    map[string]string                 // container -> name
+	handler  func(a *ext.Alert)
+	alertLog io.Writer
+}
+
+func newAlertRule(cfg *config.Alert) (*alertRule, error) {
+	parts := strings.Fields(cfg.Expr)
+
+	r := &alertRule{
+		name: cfg.Name,
+		expr: cfg.Expr,
+		rule: cfg.Rule,
+	}
+
+	switch {
+	case len(parts) == 2 && parts[1] == opIncreasing:
+		r.metric, r.op = parts[0], opIncreasing
+	case len(parts) == 3:
+		r.metric, r.op = parts[0], parts[1]
+
+		switch r.op {
+		case ">", ">=", "<", "<=", "==":
+		default:
+			return nil, fmt.Errorf("unknown alert operator: alert=%s op=%s", cfg.Name, r.op)
+		}
+
+		t, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alert threshold: alert=%s err=%s", cfg.Name, err)
+		}
+		r.threshold = t
+	default:
+		return nil, fmt.Errorf("invalid alert expression: alert=%s expr=%q", cfg.Name, cfg.Expr)
+	}
+
+	if _, ok := alertMetrics[r.metric]; !ok {
+		return nil, fmt.Errorf("unknown alert metric: alert=%s metric=%s", cfg.Name, r.metric)
+	}
+
+	if cfg.For != "" {
+		d, err := time.ParseDuration(cfg.For)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse alert duration: alert=%s err=%s", cfg.Name, err)
+		}
+		r.forDuration = d
+	}
+
+	return r, nil
+}
+
+func newAlertEvaluator(cfgs []*config.Alert, logPath string) (*alertEvaluator, error) {
+	e := &alertEvaluator{
+		lock:   &sync.Mutex{},
+		states: map[string]map[string]*alertState{},
+		names:  map[string]string{},
+	}
+
+	for _, cfg := range cfgs {
+		r, err := newAlertRule(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		e.rules = append(e.rules, r)
+	}
+
+	if logPath != "" {
+		f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open alert log: %s", err)
+		}
+
+		e.alertLog = f
+	}
+
+	return e, nil
+}
+
+// condition returns true if the alert condition holds for the value and
+// the previous sample
+func (r *alertRule) condition(value float64, prev *alertState) bool {
+	switch r.op {
+	case opIncreasing:
+		return prev.sampled && value > prev.value
+	case ">":
+		return value > r.threshold
+	case ">=":
+		return value >= r.threshold
+	case "<":
+		return value < r.threshold
+	case "<=":
+		return value <= r.threshold
+	case "==":
+		return value == r.threshold
+	}
+
+	return false
+}
+
+// evaluate evaluates the alerts for the container stats.  rule is the
+// name of the include rule matched by the container.
+func (e *alertEvaluator) evaluate(c types.ContainerJSON, rule string, stats *types.StatsJSON, now time.Time) {
+	if len(e.rules) == 0 {
+		return
+	}
+
+	alerts := []*ext.Alert{}
+
+	e.lock.Lock()
+	states, ok := e.states[c.ID]
+	if !ok {
+		states = map[string]*alertState{}
+		e.states[c.ID] = states
+	}
+	e.names[c.ID] = strings.TrimPrefix(c.Name, "/")
+
+	for _, r := range e.rules {
+		if r.rule != "" && r.rule != rule {
+			continue
+		}
+
+		st, ok := states[r.name]
+		if !ok {
+			st = &alertState{}
+			states[r.name] = st
+		}
+
+		value := alertMetrics[r.metric](stats)
+		cond := r.condition(value, st)
+		st.value, st.sampled, st.rule = value, true, rule
+
+		if !cond {
+			if st.firing {
+				alerts = append(alerts, e.alert(r, c.ID, st, ext.AlertResolved, now))
+			}
+
+			st.since = time.Time{}
+			st.firing = false
+			continue
+		}
+
+		if st.since.IsZero() {
+			st.since = now
+		}
+
+		// alerts are only sent once when they fire
+		if !st.firing && now.Sub(st.since) >= r.forDuration {
+			st.firing = true
+			alerts = append(alerts, e.alert(r, c.ID, st, ext.AlertFiring, now))
+		}
+	}
+	e.lock.Unlock()
+
+	for _, a := range alerts {
+		e.send(a)
+	}
+}
+
+// remove resolves the firing alerts of a removed container and resets the
+// alert state
+func (e *alertEvaluator) remove(id string, now time.Time) {
+	alerts := []*ext.Alert{}
+
+	e.lock.Lock()
+	for _, r := range e.rules {
+		if st, ok := e.states[id][r.name]; ok && st.firing {
+			alerts = append(alerts, e.alert(r, id, st, ext.AlertResolved, now))
+		}
+	}
+	delete(e.states, id)
+	delete(e.names, id)
+	e.lock.Unlock()
+
+	for _, a := range alerts {
+		e.send(a)
+	}
+}
+
+func (e *alertEvaluator) setHandler(fn func(a *ext.Alert)) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.handler = fn
+}
+
+func (e *alertEvaluator) alert(r *alertRule, id string, st *alertState, state string, now time.Time) *ext.Alert {
+	return &ext.Alert{
+		Name:          r.name,
+		Ext:           pluginName,
+		State:         state,
+		Expr:          r.expr,
+		Rule:          st.rule,
+		Container:     id,
+		ContainerName: e.names[id],
+		Value:         st.value,
+		Since:         st.since,
+		Time:          now,
+	}
+}
+
+// send logs the alert, writes it to the alert log and calls the handler
+func (e *alertEvaluator) send(a *ext.Alert) {
+	l := log().WithField("container_id", a.Container)
+	msg := fmt.Sprintf("alert %s: name=%s expr=%q value=%.2f", a.State, a.Name, a.Expr, a.Value)
+	if a.State == ext.AlertFiring {
+		l.Warn(msg)
+	} else {
+		l.Info(msg)
+	}
+
+	e.lock.Lock()
+	handler := e.handler
+	if e.alertLog != nil {
+		data, err := json.Marshal(a)
+		if err == nil {
+			_, err = e.alertLog.Write(append(data, '\n'))
+		}
+
+		if err != nil {
+			log().Errorf("unable to write alert log: %s", err)
+		}
+	}
+	e.lock.Unlock()
+
+	if handler != nil {
+		handler(a)
+	}
+}
+
+func memoryPercent(s *types.StatsJSON) float64 {
+	if s.MemoryStats.Limit == 0 {
+		return 0
+	}
+
+	return float64(s.MemoryStats.Usage) / float64(s.MemoryStats.Limit) * 100.0
+}
+
+// cpuPercent returns the cpu usage since the previous stats read by the
+// docker daemon
+func cpuPercent(s *types.StatsJSON) float64 {
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+
+	return cpuDelta / systemDelta * float64(len(s.CPUStats.CPUUsage.PercpuUsage)) * 100.0
+}
+
+// networkTotal returns the sum of the counter over the container networks
+func networkTotal(counter func(n types.NetworkStats) uint64) func(s *types.StatsJSON) float64 {
+	return func(s *types.StatsJSON) float64 {
+		total := uint64(0)
+		for _, n := range s.Networks {
+			total += counter(n)
+		}
+
+		return float64(total)
+	}
+}
@@ -0,0 +1,210 @@
+package beacon
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext"
+)
+
+func testAlertEvaluator(t *testing.T, logPath string, alerts ...*config.Alert) (*alertEvaluator, *[]*ext.Alert) {
+	e, err := newAlertEvaluator(alerts, logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := []*ext.Alert{}
+	e.setHandler(func(a *ext.Alert) {
+		sent = append(sent, a)
+	})
+
+	return e, &sent
+}
+
+func memoryStats(usage uint64) *types.StatsJSON {
+	s := &types.StatsJSON{}
+	s.MemoryStats.Usage = usage
+	s.MemoryStats.Limit = 100
+
+	return s
+}
+
+func rxErrorStats(errors uint64) *types.StatsJSON {
+	s := &types.StatsJSON{}
+	s.Networks = map[string]types.NetworkStats{
+		"eth0": {RxErrors: errors},
+	}
+
+	return s
+}
+
+func TestAlertThreshold(t *testing.T) {
+	e, sent := testAlertEvaluator(t, "", &config.Alert{
+		Name: "memory",
+		Expr: "memory_usage_percent > 90",
+		For:  "5m",
+	})
+
+	c := testContainer("web", "nginx", nil)
+	c.ID = "0123456789ab"
+	now := time.Now()
+
+	e.evaluate(c, "", memoryStats(95), now)
+	e.evaluate(c, "", memoryStats(96), now.Add(time.Minute*4))
+	if len(*sent) != 0 {
+		t.Fatalf("expected no alert before 5m; received %d", len(*sent))
+	}
+
+	e.evaluate(c, "", memoryStats(97), now.Add(time.Minute*5))
+	// firing alerts are only sent once
+	e.evaluate(c, "", memoryStats(98), now.Add(time.Minute*6))
+	if len(*sent) != 1 {
+		t.Fatalf("expected 1 alert; received %d", len(*sent))
+	}
+
+	a := (*sent)[0]
+	if a.State != ext.AlertFiring || a.Name != "memory" || a.ContainerName != "web" || a.Value != 97 {
+		t.Fatalf("unexpected alert: %+v", a)
+	}
+
+	if !a.Since.Equal(now) {
+		t.Fatalf("expected alert since %s; received %s", now, a.Since)
+	}
+
+	e.evaluate(c, "", memoryStats(50), now.Add(time.Minute*7))
+	if len(*sent) != 2 || (*sent)[1].State != ext.AlertResolved {
+		t.Fatalf("expected resolved alert; received %+v", *sent)
+	}
+}
+
+func TestAlertThresholdReset(t *testing.T) {
+	e, sent := testAlertEvaluator(t, "", &config.Alert{
+		Expr: "memory_usage_percent >= 90",
+		For:  "5m",
+	})
+
+	c := testContainer("web", "nginx", nil)
+	now := time.Now()
+
+	e.evaluate(c, "", memoryStats(95), now)
+	e.evaluate(c, "", memoryStats(10), now.Add(time.Minute*3))
+	e.evaluate(c, "", memoryStats(95), now.Add(time.Minute*6))
+	if len(*sent) != 0 {
+		t.Fatalf("expected no alert after the condition reset; received %d", len(*sent))
+	}
+}
+
+func TestAlertIncreasing(t *testing.T) {
+	e, sent := testAlertEvaluator(t, "", &config.Alert{
+		Name: "rx_errors",
+		Expr: "network_rx_errors increasing",
+	})
+
+	c := testContainer("web", "nginx", nil)
+	now := time.Now()
+
+	e.evaluate(c, "", rxErrorStats(3), now)
+	if len(*sent) != 0 {
+		t.Fatal("expected no alert on the first sample")
+	}
+
+	e.evaluate(c, "", rxErrorStats(5), now.Add(time.Second*30))
+	e.evaluate(c, "", rxErrorStats(5), now.Add(time.Minute))
+
+	if len(*sent) != 2 || (*sent)[0].State != ext.AlertFiring || (*sent)[1].State != ext.AlertResolved {
+		t.Fatalf("expected firing and resolved alerts; received %+v", *sent)
+	}
+}
+
+func TestAlertRule(t *testing.T) {
+	e, sent := testAlertEvaluator(t, "", &config.Alert{
+		Expr: "memory_usage_percent > 90",
+		Rule: "web",
+	})
+
+	now := time.Now()
+	e.evaluate(testContainer("db", "postgres", nil), "db", memoryStats(95), now)
+	e.evaluate(testContainer("web", "nginx", nil), "web", memoryStats(95), now)
+
+	if len(*sent) != 1 || (*sent)[0].ContainerName != "web" || (*sent)[0].Rule != "web" {
+		t.Fatalf("expected alert for the web rule only; received %+v", *sent)
+	}
+}
+
+func TestAlertRemove(t *testing.T) {
+	e, sent := testAlertEvaluator(t, "", &config.Alert{
+		Expr: "memory_usage_percent > 90",
+	})
+
+	c := testContainer("web", "nginx", nil)
+	c.ID = "0123456789ab"
+	now := time.Now()
+
+	e.evaluate(c, "", memoryStats(95), now)
+	e.remove(c.ID, now.Add(time.Minute))
+	e.remove(c.ID, now.Add(time.Minute))
+
+	if len(*sent) != 2 || (*sent)[1].State != ext.AlertResolved {
+		t.Fatalf("expected the alert to resolve once; received %+v", *sent)
+	}
+}
+
+func TestAlertLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "interlock-alerts-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "alerts.json")
+	e, _ := testAlertEvaluator(t, p, &config.Alert{
+		Name: "memory",
+		Expr: "memory_usage_percent > 90",
+	})
+
+	e.evaluate(testContainer("web", "nginx", nil), "", memoryStats(95), time.Now())
+
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		t.Fatal("expected alert in the alert log")
+	}
+
+	var a *ext.Alert
+	if err := json.Unmarshal(scanner.Bytes(), &a); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Name != "memory" || a.State != ext.AlertFiring {
+		t.Fatalf("unexpected alert: %+v", a)
+	}
+}
+
+func TestNewAlertRuleInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"memory_usage_percent",
+		"memory_usage_percent != 90",
+		"memory_usage_percent > high",
+		"disk_usage_percent > 90",
+	} {
+		if _, err := newAlertRule(&config.Alert{Expr: expr}); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+
+	if _, err := newAlertRule(&config.Alert{Expr: "memory_usage_percent > 90", For: "5 minutes"}); err == nil {
+		t.Fatal("expected error for invalid duration")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/docker/engine-api/types"
 	etypes "github.com/docker/engine-api/types/events"
 	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext"
 	"github.com/ehazlett/interlock/utils"
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/context"
@@ -27,6 +28,8 @@ type Beacon struct {
 	cfg       *config.ExtensionConfig
 	client    *client.Client
 	monitored map[string]int
+	rules     *ruleSet
+	alerts    *alertEvaluator
 }
 
 func log() *logrus.Entry {
@@ -50,10 +53,26 @@ func NewBeacon(c *config.ExtensionConfig, cl *client.Client) (*Beacon, error) {
 		}
 	}()
 
+	rules, err := newRuleSet(c.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if rules.includes == 0 {
+		log().Warn("no include rules configured; no containers will be monitored")
+	}
+
+	alerts, err := newAlertEvaluator(c.Alerts, c.AlertLogPath)
+	if err != nil {
+		return nil, err
+	}
+
 	ext := &Beacon{
 		cfg:       c,
 		client:    cl,
 		monitored: map[string]int{},
+		rules:     rules,
+		alerts:    alerts,
 	}
 
 	containerID, err := utils.GetContainerID()
@@ -108,11 +127,12 @@ func (b *Beacon) HandleEvent(event *etypes.Message) error {
 
 		log().Debugf("containers: %v", b.monitored)
 	case "start":
-		log().Debugf("checking container for stats: id=%s", event.ID)
+		log().WithField("container_id", event.ID).Debug("checking container for stats")
 		b.monitored[event.ID] = 1
 	case "kill", "die", "stop", "destroy":
-		log().Debugf("resetting stats: id=%s", event.ID)
+		log().WithField("container_id", event.ID).Debug("resetting stats")
 		delete(b.monitored, event.ID)
+		b.alerts.remove(event.ID, time.Now())
 
 		if err := b.resetStats(event.ID); err != nil {
 			return err
@@ -121,3 +141,8 @@ func (b *Beacon) HandleEvent(event *etypes.Message) error {
 
 	return nil
 }
+
+// SetAlertHandler sets the func called when an alert fires or resolves
+func (b *Beacon) SetAlertHandler(fn func(a *ext.Alert)) {
+	b.alerts.setHandler(fn)
+}
@@ -1,62 +1,204 @@
 package beacon
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
 
-	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/config"
 )
 
-func (b *Beacon) ruleMatch(cfg *ctypes.Config) bool {
-	// iterate through rules and check type / match
-	isMatch := false
-
-	for _, rule := range b.cfg.Rules {
-		switch rule.Type {
-		case "label":
-			// TODO: match label
-			m := b.isLabelMatch(rule.Regex, cfg)
-			if m {
-				isMatch = m
-				break
-			}
-		case "name":
-			// TODO: match name
-			m := b.isNameMatch(rule.Regex, cfg)
-			if m {
-				isMatch = m
-				break
-			}
-		case "image":
-			// TODO: match name
-			m := b.isImageMatch(rule.Regex, cfg)
-			if m {
-				isMatch = m
-				break
-			}
-		default:
-			log().Errorf("unknown rule type: %s", rule.Type)
-		}
-	}
-
-	return isMatch
-}
-
-func (b *Beacon) isLabelMatch(rule string, cfg *ctypes.Config) bool {
-	log().Warnf("isLabelMatch not implemented")
-	return false
+const (
+	ruleInclude = "include"
+	ruleExclude = "exclude"
+)
+
+// rule is a compiled config rule
+type rule struct {
+	name          string
+	exclude       bool
+	labels        map[string]*regexp.Regexp // nil regex matches any value
+	containerName *regexp.Regexp
+	image         *regexp.Regexp
+	network       *regexp.Regexp
 }
 
-func (b *Beacon) isNameMatch(rule string, cfg *ctypes.Config) bool {
-	log().Warnf("isNameMatch not implemented")
-	return false
+// ruleSet selects the monitored containers.  Containers are monitored if
+// they match an include rule and no exclude rule.
+type ruleSet struct {
+	rules    []*rule
+	includes int
+}
+
+func newRuleSet(cfgs []*config.Rule) (*ruleSet, error) {
+	s := &ruleSet{}
+	for _, cfg := range cfgs {
+		r, err := newRule(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		if !r.exclude {
+			s.includes++
+		}
+
+		s.rules = append(s.rules, r)
+	}
+
+	return s, nil
 }
 
-func (b *Beacon) isImageMatch(rule string, cfg *ctypes.Config) bool {
-	image := cfg.Image
+func newRule(cfg *config.Rule) (*rule, error) {
+	r := &rule{
+		name:   cfg.Name,
+		labels: map[string]*regexp.Regexp{},
+	}
+
+	switch cfg.Action {
+	case ruleInclude, "":
+	case ruleExclude:
+		r.exclude = true
+	default:
+		return nil, fmt.Errorf("unknown rule action: rule=%s action=%s", cfg.Name, cfg.Action)
+	}
+
+	for _, l := range cfg.Labels {
+		parts := strings.SplitN(l, "=", 2)
+		if len(parts) == 1 {
+			r.labels[parts[0]] = nil
+			continue
+		}
 
-	r := regexp.MustCompile(rule)
+		re, err := compileRule(cfg.Name, "label", parts[1])
+		if err != nil {
+			return nil, err
+		}
 
-	m := r.MatchString(image)
+		r.labels[parts[0]] = re
+	}
 
-	return m
+	var err error
+	if r.containerName, err = compileRule(cfg.Name, "name", cfg.ContainerName); err != nil {
+		return nil, err
+	}
+
+	if r.image, err = compileRule(cfg.Name, "image", cfg.Image); err != nil {
+		return nil, err
+	}
+
+	if r.network, err = compileRule(cfg.Name, "network", cfg.Network); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// compileRule compiles the matcher regex; empty matchers are not compiled
+func compileRule(name, matcher, expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s rule: rule=%s err=%s", matcher, name, err)
+	}
+
+	return re, nil
+}
+
+// match returns true if the container should be monitored and the name of
+// the matching include rule
+func (s *ruleSet) match(c types.ContainerJSON) (string, bool) {
+	var include *rule
+	for _, r := range s.rules {
+		if !r.matches(c) {
+			continue
+		}
+
+		if r.exclude {
+			return "", false
+		}
+
+		if include == nil {
+			include = r
+		}
+	}
+
+	if include == nil {
+		return "", false
+	}
+
+	return include.name, true
+}
+
+// matches returns true if all matchers of the rule match the container
+func (r *rule) matches(c types.ContainerJSON) bool {
+	return r.isLabelMatch(c) && r.isNameMatch(c) && r.isImageMatch(c) && r.isNetworkMatch(c)
+}
+
+func (r *rule) isLabelMatch(c types.ContainerJSON) bool {
+	if len(r.labels) == 0 {
+		return true
+	}
+
+	if c.Config == nil {
+		return false
+	}
+
+	for k, re := range r.labels {
+		v, ok := c.Config.Labels[k]
+		if !ok {
+			return false
+		}
+
+		if re != nil && !re.MatchString(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *rule) isNameMatch(c types.ContainerJSON) bool {
+	if r.containerName == nil {
+		return true
+	}
+
+	if c.ContainerJSONBase == nil {
+		return false
+	}
+
+	return r.containerName.MatchString(strings.TrimPrefix(c.Name, "/"))
+}
+
+func (r *rule) isImageMatch(c types.ContainerJSON) bool {
+	if r.image == nil {
+		return true
+	}
+
+	if c.Config == nil {
+		return false
+	}
+
+	return r.image.MatchString(c.Config.Image)
+}
+
+func (r *rule) isNetworkMatch(c types.ContainerJSON) bool {
+	if r.network == nil {
+		return true
+	}
+
+	if c.NetworkSettings == nil {
+		return false
+	}
+
+	for name := range c.NetworkSettings.Networks {
+		if r.network.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,168 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/docker/engine-api/types/network"
+	"github.com/ehazlett/interlock/config"
+)
+
+func testContainer(name, image string, labels map[string]string, networks ...string) types.ContainerJSON {
+	nets := map[string]*network.EndpointSettings{}
+	for _, n := range networks {
+		nets[n] = &network.EndpointSettings{}
+	}
+
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			Name: "/" + name,
+		},
+		Config: &ctypes.Config{
+			Image:  image,
+			Labels: labels,
+		},
+		NetworkSettings: &types.NetworkSettings{
+			Networks: nets,
+		},
+	}
+}
+
+func testRuleSet(t *testing.T, rules ...*config.Rule) *ruleSet {
+	s, err := newRuleSet(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestRuleMatchNoRules(t *testing.T) {
+	s := testRuleSet(t)
+
+	if _, ok := s.match(testContainer("web", "nginx:latest", nil)); ok {
+		t.Fatal("expected no containers to match without rules")
+	}
+}
+
+func TestRuleMatchLabel(t *testing.T) {
+	s := testRuleSet(t, &config.Rule{
+		Name:   "web",
+		Labels: []string{"com.example.team=^web", "com.example.env"},
+	})
+
+	rule, ok := s.match(testContainer("app", "nginx", map[string]string{
+		"com.example.team": "web-frontend",
+		"com.example.env":  "prod",
+	}))
+	if !ok || rule != "web" {
+		t.Fatalf("expected match for rule web; received rule=%q match=%v", rule, ok)
+	}
+
+	if _, ok := s.match(testContainer("app", "nginx", map[string]string{"com.example.team": "web"})); ok {
+		t.Fatal("expected no match without the com.example.env label")
+	}
+
+	if _, ok := s.match(testContainer("app", "nginx", map[string]string{"com.example.team": "db", "com.example.env": "prod"})); ok {
+		t.Fatal("expected no match for label value db")
+	}
+}
+
+func TestRuleMatchName(t *testing.T) {
+	s := testRuleSet(t, &config.Rule{
+		ContainerName: "^web-\\d+$",
+	})
+
+	if _, ok := s.match(testContainer("web-1", "nginx", nil)); !ok {
+		t.Fatal("expected match for web-1")
+	}
+
+	if _, ok := s.match(testContainer("db-1", "postgres", nil)); ok {
+		t.Fatal("expected no match for db-1")
+	}
+}
+
+func TestRuleMatchImageAndNetwork(t *testing.T) {
+	s := testRuleSet(t, &config.Rule{
+		Image:   "^nginx",
+		Network: "^frontend$",
+	})
+
+	if _, ok := s.match(testContainer("web", "nginx:1.11", nil, "backend", "frontend")); !ok {
+		t.Fatal("expected match for nginx on frontend")
+	}
+
+	if _, ok := s.match(testContainer("web", "nginx:1.11", nil, "backend")); ok {
+		t.Fatal("expected no match without the frontend network")
+	}
+
+	if _, ok := s.match(testContainer("db", "postgres", nil, "frontend")); ok {
+		t.Fatal("expected no match for postgres")
+	}
+}
+
+func TestRuleMatchExclude(t *testing.T) {
+	s := testRuleSet(t,
+		&config.Rule{
+			Name:  "all",
+			Image: ".*",
+		},
+		&config.Rule{
+			Name:   "system",
+			Action: ruleExclude,
+			Labels: []string{"com.example.system=true"},
+		},
+	)
+
+	if rule, ok := s.match(testContainer("web", "nginx", nil)); !ok || rule != "all" {
+		t.Fatalf("expected match for rule all; received rule=%q match=%v", rule, ok)
+	}
+
+	if _, ok := s.match(testContainer("agent", "agent", map[string]string{"com.example.system": "true"})); ok {
+		t.Fatal("expected excluded container to not match")
+	}
+}
+
+func TestRuleMatchExcludeOnly(t *testing.T) {
+	s := testRuleSet(t, &config.Rule{
+		Action:        ruleExclude,
+		ContainerName: "^interlock",
+	})
+
+	if _, ok := s.match(testContainer("web", "nginx", nil)); ok {
+		t.Fatal("expected no match without include rules")
+	}
+
+	if _, ok := s.match(testContainer("interlock", "ehazlett/interlock", nil)); ok {
+		t.Fatal("expected interlock to be excluded")
+	}
+}
+
+func TestRuleMatchIncludeAll(t *testing.T) {
+	s := testRuleSet(t, &config.Rule{
+		Name: "all",
+	}, &config.Rule{
+		Action:        ruleExclude,
+		ContainerName: "^interlock",
+	})
+
+	rule, ok := s.match(testContainer("web", "nginx", nil))
+	if !ok || rule != "all" {
+		t.Fatalf("expected match for rule all; received rule=%q match=%v", rule, ok)
+	}
+
+	if _, ok := s.match(testContainer("interlock", "ehazlett/interlock", nil)); ok {
+		t.Fatal("expected interlock to be excluded")
+	}
+}
+
+func TestNewRuleSetInvalid(t *testing.T) {
+	if _, err := newRuleSet([]*config.Rule{{Image: "("}}); err == nil {
+		t.Fatal("expected error for invalid regex")
+	}
+
+	if _, err := newRuleSet([]*config.Rule{{Action: "ignore"}}); err == nil {
+		t.Fatal("expected error for unknown action")
+	}
+}
@@ -29,7 +29,7 @@ type Stat struct {
 }
 
 func (b *Beacon) sendContainerStats(id string, stats *types.StatsJSON, ec chan error, args ...interface{}) {
-	log().Debugf("updating container stats: id=%s", id)
+	log().WithField("container_id", id).Debug("updating container stats")
 
 	image := ""
 	if len(id) >= 12 {
@@ -493,15 +493,14 @@ func (b *Beacon) collectStats() {
 				return
 			}
 
-			image := c.Config.Image
-
 			// match rules
-			if !b.ruleMatch(c.Config) {
-				log().Debugf("unable to find rule matching container %s (%s); not monitoring", c.ID, image)
+			rule, ok := b.rules.match(c)
+			if !ok {
+				log().WithField("container_id", cID).Debugf("container does not match rules; not monitoring: image=%s", c.Config.Image)
 				return
 			}
 
-			log().Debugf("checking container stats: id=%s", cID)
+			log().WithField("container_id", cID).Debugf("checking container stats: rule=%s", rule)
 			r, err := b.client.ContainerStats(context.Background(), cID, false)
 			if err != nil {
 				log().Errorf("unable to get container stats: %s", err)
@@ -516,6 +515,7 @@ func (b *Beacon) collectStats() {
 					return
 				}
 
+				b.alerts.evaluate(c, rule, stats, time.Now())
 				b.sendContainerStats(cID, stats, errChan)
 			}
 
@@ -2,6 +2,7 @@ package ext
 
 import (
 	etypes "github.com/docker/engine-api/types/events"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -14,16 +15,40 @@ const (
 	InterlockSSLOnlyLabel             = "interlock.ssl_only"               // haproxy, nginx
 	InterlockSSLBackendLabel          = "interlock.ssl_backend"            // haproxy, nginx
 	InterlockSSLBackendTLSVerifyLabel = "interlock.ssl_backend_tls_verify" // haproxy, nginx
-	InterlockSSLCertLabel             = "interlock.ssl_cert"               // nginx
-	InterlockSSLCertKeyLabel          = "interlock.ssl_cert_key"           // nginx
+	InterlockSSLCertLabel             = "interlock.ssl_cert"               // haproxy, nginx
+	InterlockSSLCertKeyLabel          = "interlock.ssl_cert_key"           // haproxy, nginx
+	InterlockSSLSecretLabel           = "interlock.ssl_secret"             // haproxy, nginx
 	InterlockPortLabel                = "interlock.port"                   // haproxy, nginx
 	InterlockWebsocketEndpointLabel   = "interlock.websocket_endpoint"     // nginx
 	InterlockAliasDomainLabel         = "interlock.alias_domain"           // haproxy, nginx
 	InterlockHealthCheckLabel         = "interlock.health_check"           // haproxy
-	InterlockHealthCheckIntervalLabel = "interlock.health_check_interval"  // haproxy
+	InterlockHealthCheckIntervalLabel = "interlock.health_check_interval"  // haproxy, nginx
+	InterlockHealthCheckPathLabel     = "interlock.health_check_path"      // haproxy, nginx
+	InterlockHealthCheckStatusLabel   = "interlock.health_check_status"    // haproxy, nginx
+	InterlockHealthCheckRiseLabel     = "interlock.health_check_rise"      // haproxy, nginx
+	InterlockHealthCheckFallLabel     = "interlock.health_check_fall"      // haproxy, nginx
+	InterlockHealthCheckTimeoutLabel  = "interlock.health_check_timeout"   // haproxy, nginx
 	InterlockBalanceAlgorithmLabel    = "interlock.balance_algorithm"      // haproxy
 	InterlockBackendOptionLabel       = "interlock.backend_option"         // haproxy
 	InterlockIPHashLabel              = "interlock.ip_hash"                // nginx
+	InterlockStickyLabel              = "interlock.sticky"                 // haproxy, nginx
+	InterlockRateLimitLabel           = "interlock.rate_limit"             // haproxy, nginx
+	InterlockConnLimitLabel           = "interlock.conn_limit"             // haproxy, nginx
+	InterlockLimitKeyLabel            = "interlock.limit_key"              // haproxy, nginx
+	InterlockAllowLabel               = "interlock.allow"                  // haproxy, nginx
+	InterlockDenyLabel                = "interlock.deny"                   // haproxy, nginx
+	InterlockBasicAuthLabel           = "interlock.basic_auth"             // haproxy, nginx
+	InterlockRequestHeaderLabel       = "interlock.request_header"         // haproxy, nginx
+	InterlockResponseHeaderLabel      = "interlock.response_header"        // haproxy, nginx
+	InterlockCORSLabel                = "interlock.cors"                   // haproxy, nginx
+	InterlockProtocolLabel            = "interlock.protocol"               // haproxy, nginx
+	InterlockRedirectLabel            = "interlock.redirect"               // haproxy, nginx
+	InterlockRedirectCodeLabel        = "interlock.redirect_code"          // haproxy, nginx
+	InterlockStaticResponseLabel      = "interlock.static_response"        // haproxy, nginx
+	InterlockStaticBodyLabel          = "interlock.static_body"            // haproxy, nginx
+	InterlockMaintenanceLabel         = "interlock.maintenance"            // haproxy, nginx
+	InterlockDrainLabel               = "interlock.drain"                  // haproxy, nginx
+	InterlockErrorPageLabel           = "interlock.error_page"             // haproxy, nginx
 	InterlockContextRootLabel         = "interlock.context_root"           // haproxy, nginx
 	InterlockContextRootRewriteLabel  = "interlock.context_root_rewrite"   // haproxy, nginx
 )
@@ -32,3 +57,18 @@ type Extension interface {
 	Name() string
 	HandleEvent(event *etypes.Message) error
 }
+
+// MaintenanceExtension is implemented by extensions that can put domains
+// into maintenance mode
+type MaintenanceExtension interface {
+	Extension
+	MaintenanceDomains() []string
+	SetMaintenance(domain string, enabled bool)
+}
+
+// TracedExtension is implemented by extensions that trace the event
+// handling as a child of the span in the context
+type TracedExtension interface {
+	Extension
+	HandleEventContext(ctx context.Context, event *etypes.Message) error
+}
@@ -0,0 +1,119 @@
+package lb
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/ehazlett/interlock/ext/lb/accesslog"
+)
+
+const (
+	unknownDomain = "unknown"
+)
+
+// AccessLogParser is implemented by backends that can parse the access
+// logs the proxy containers send to the syslog receiver
+type AccessLogParser interface {
+	ParseAccessLog(line string) (*accesslog.Entry, error)
+}
+
+// startAccessLog starts the syslog receiver for the proxy access logs and
+// sets the endpoint the proxy configs send the logs to
+func (l *LoadBalancer) startAccessLog() error {
+	parser, ok := l.backend.(AccessLogParser)
+	if !ok {
+		return fmt.Errorf("access logs are not supported by the %s backend", l.backend.Name())
+	}
+
+	var fwd *accesslog.Forwarder
+	if l.cfg.AccessLogForward != "" {
+		f, err := accesslog.NewForwarder(l.cfg.AccessLogForward)
+		if err != nil {
+			return err
+		}
+
+		fwd = f
+	}
+
+	r, err := accesslog.NewReceiver(l.cfg.AccessLogAddr, func(m *accesslog.Message) {
+		l.handleAccessLog(parser, fwd, m)
+	})
+	if err != nil {
+		return err
+	}
+
+	if l.cfg.AccessLogEndpoint == "" {
+		endpoint, err := l.accessLogEndpoint(r.Addr())
+		if err != nil {
+			r.Close()
+			return err
+		}
+
+		l.cfg.AccessLogEndpoint = endpoint
+	}
+
+	log().Infof("access log receiver started: addr=%s endpoint=%s", r.Addr(), l.cfg.AccessLogEndpoint)
+
+	return nil
+}
+
+// accessLogEndpoint returns the syslog address for the proxies.  This is
+// the listen address or the address of the interlock container if the
+// receiver listens on all interfaces.
+func (l *LoadBalancer) accessLogEndpoint(addr net.Addr) (string, error) {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "", err
+	}
+
+	if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+		return addr.String(), nil
+	}
+
+	networks, err := l.nodeNetworks()
+	if err != nil {
+		return "", fmt.Errorf("unable to detect access log endpoint: %s", err)
+	}
+
+	names := []string{}
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) > 0 {
+		return net.JoinHostPort(networks[names[0]], port), nil
+	}
+
+	return "", fmt.Errorf("unable to detect access log endpoint; set AccessLogEndpoint to the address of the receiver")
+}
+
+// handleAccessLog updates the request metrics for the access log message
+// and forwards the entry
+func (l *LoadBalancer) handleAccessLog(parser AccessLogParser, fwd *accesslog.Forwarder, m *accesslog.Message) {
+	name := l.backend.Name()
+
+	e, err := parser.ParseAccessLog(m.Content)
+	if err != nil {
+		accessLogErrors.WithLabelValues(name).Inc()
+		log().Debugf("unable to parse access log: proxy=%s err=%s", m.Source, err)
+		return
+	}
+
+	e.Ext = name
+	e.Proxy = m.Source
+
+	domain := e.Domain
+	if domain == "" {
+		domain = unknownDomain
+	}
+
+	accessRequests.WithLabelValues(name, domain, accesslog.StatusClass(e.Status)).Inc()
+	accessRequestDuration.WithLabelValues(name, domain).Observe(e.Duration)
+	accessResponseBytes.WithLabelValues(name, domain).Add(float64(e.Bytes))
+
+	if fwd != nil {
+		fwd.Forward(e)
+	}
+}
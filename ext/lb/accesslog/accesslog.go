@@ -0,0 +1,154 @@
+package accesslog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	pluginName = "accesslog"
+
+	nginxTimeLayout   = "02/Jan/2006:15:04:05 -0700"
+	haproxyTimeLayout = "02/Jan/2006:15:04:05.000"
+)
+
+var (
+	// $proxy_host $host $remote_addr [$time_local] "$request" $status
+	// $body_bytes_sent $request_time "$http_user_agent"
+	nginxRegex = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "([^"]*)" (\d{3}) (\d+) ([\d.]+) "([^"]*)"$`)
+
+	// haproxy httplog format:
+	// client:port [accept_date] frontend backend/server Tq/Tw/Tc/Tr/Tt
+	// status bytes req_cookie res_cookie termination_state
+	// actconn/feconn/beconn/srv_conn/retries srv_queue/backend_queue
+	// {captured headers} "request"
+	haproxyRegex = regexp.MustCompile(`^(\S+) \[([^\]]+)\] (\S+) ([^/\s]+)/(\S+) -?\d+/-?\d+/-?\d+/-?\d+/\+?(-?\d+) (-?\d+) \+?(\d+) \S+ \S+ \S+ \S+ \S+ (?:\{[^}]*\} )*"([^"]*)"$`)
+)
+
+func log() *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{
+		"ext": pluginName,
+	})
+}
+
+// Entry is a request parsed from a proxy access log
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Ext       string    `json:"ext"`
+	Proxy     string    `json:"proxy,omitempty"` // address of the proxy container
+	Domain    string    `json:"domain,omitempty"`
+	Host      string    `json:"host,omitempty"`     // request host (nginx)
+	Upstream  string    `json:"upstream,omitempty"` // nginx upstream or haproxy backend
+	Server    string    `json:"server,omitempty"`   // haproxy server
+	Client    string    `json:"client"`
+	Method    string    `json:"method,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Protocol  string    `json:"protocol,omitempty"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	Duration  float64   `json:"duration"` // seconds
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+// ParseNginx parses an access log line in the interlock nginx log format
+func ParseNginx(line string) (*Entry, error) {
+	m := nginxRegex.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("invalid nginx access log: %q", line)
+	}
+
+	t, err := time.Parse(nginxTimeLayout, m[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid nginx access log time: %s", err)
+	}
+
+	status, _ := strconv.Atoi(m[6])
+	bytes, _ := strconv.ParseInt(m[7], 10, 64)
+	duration, err := strconv.ParseFloat(m[8], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nginx request time: %s", err)
+	}
+
+	e := &Entry{
+		Time:      t,
+		Upstream:  dash(m[1]),
+		Host:      dash(m[2]),
+		Client:    m[3],
+		Status:    status,
+		Bytes:     bytes,
+		Duration:  duration,
+		UserAgent: dash(m[9]),
+	}
+	e.Method, e.Path, e.Protocol = parseRequest(m[5])
+
+	return e, nil
+}
+
+// ParseHAProxy parses an access log line in the haproxy httplog format
+func ParseHAProxy(line string) (*Entry, error) {
+	m := haproxyRegex.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("invalid haproxy access log: %q", line)
+	}
+
+	t, err := time.Parse(haproxyTimeLayout, m[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid haproxy access log time: %s", err)
+	}
+
+	// the total time is -1 for aborted requests
+	total, _ := strconv.Atoi(m[6])
+	if total < 0 {
+		total = 0
+	}
+
+	status, _ := strconv.Atoi(m[7])
+	bytes, _ := strconv.ParseInt(m[8], 10, 64)
+
+	e := &Entry{
+		Time:     t,
+		Client:   m[1],
+		Upstream: m[4],
+		Server:   m[5],
+		Status:   status,
+		Bytes:    bytes,
+		Duration: float64(total) / 1000,
+	}
+	e.Method, e.Path, e.Protocol = parseRequest(m[9])
+
+	return e, nil
+}
+
+// StatusClass returns the status class of the response (2xx, 5xx etc)
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "none"
+	}
+
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+func parseRequest(r string) (method, path, protocol string) {
+	parts := strings.SplitN(r, " ", 3)
+	switch len(parts) {
+	case 3:
+		return parts[0], parts[1], parts[2]
+	case 2:
+		return parts[0], parts[1], ""
+	}
+
+	return "", "", ""
+}
+
+func dash(v string) string {
+	if v == "-" {
+		return ""
+	}
+
+	return v
+}
@@ -0,0 +1,104 @@
+package accesslog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseNginx(t *testing.T) {
+	line := `app_example_com example.com 10.0.0.10 [18/Oct/2026:12:00:00 +0000] "GET /api/status HTTP/1.1" 200 512 0.123 "curl/7.50.1"`
+
+	e, err := ParseNginx(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Upstream != "app_example_com" || e.Host != "example.com" || e.Client != "10.0.0.10" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	if e.Method != "GET" || e.Path != "/api/status" || e.Protocol != "HTTP/1.1" {
+		t.Fatalf("unexpected request: method=%s path=%s protocol=%s", e.Method, e.Path, e.Protocol)
+	}
+
+	if e.Status != 200 || e.Bytes != 512 || e.Duration != 0.123 {
+		t.Fatalf("unexpected response: status=%d bytes=%d duration=%f", e.Status, e.Bytes, e.Duration)
+	}
+
+	if expected := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC); !e.Time.Equal(expected) {
+		t.Fatalf("expected time %s; received %s", expected, e.Time)
+	}
+}
+
+func TestParseNginxNoUpstream(t *testing.T) {
+	line := `- example.com 10.0.0.10 [18/Oct/2026:12:00:00 +0000] "GET / HTTP/1.1" 302 161 0.000 "-"`
+
+	e, err := ParseNginx(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Upstream != "" || e.UserAgent != "" {
+		t.Fatalf("expected empty upstream and user agent: %+v", e)
+	}
+}
+
+func TestParseHAProxy(t *testing.T) {
+	line := `10.0.0.10:52314 [18/Oct/2026:12:00:00.250] http-default app_example_com/interlock_slot0 0/0/1/45/46 503 212 - - ---- 1/1/0/0/0 0/0 "POST /upload HTTP/1.1"`
+
+	e, err := ParseHAProxy(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Upstream != "app_example_com" || e.Server != "interlock_slot0" || e.Client != "10.0.0.10:52314" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	if e.Method != "POST" || e.Path != "/upload" {
+		t.Fatalf("unexpected request: method=%s path=%s", e.Method, e.Path)
+	}
+
+	if e.Status != 503 || e.Bytes != 212 || e.Duration != 0.046 {
+		t.Fatalf("unexpected response: status=%d bytes=%d duration=%f", e.Status, e.Bytes, e.Duration)
+	}
+
+	if expected := time.Date(2026, 10, 18, 12, 0, 0, 250000000, time.UTC); !e.Time.Equal(expected) {
+		t.Fatalf("expected time %s; received %s", expected, e.Time)
+	}
+}
+
+func TestParseHAProxyAborted(t *testing.T) {
+	line := `10.0.0.10:52314 [18/Oct/2026:12:00:00.250] http-default http-default/<NOSRV> -1/-1/-1/-1/+3001 -1 +0 - - CR-- 1/1/0/0/0 0/0 {example.com} "<BADREQ>"`
+
+	e, err := ParseHAProxy(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Status != -1 || e.Duration != 3.001 || e.Method != "" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	if c := StatusClass(e.Status); c != "none" {
+		t.Fatalf("expected status class none; received %s", c)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := ParseNginx("Proxy http-default started."); err == nil {
+		t.Fatal("expected error for invalid nginx log")
+	}
+
+	if _, err := ParseHAProxy("Proxy http-default started."); err == nil {
+		t.Fatal("expected error for invalid haproxy log")
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for status, expected := range map[int]string{200: "2xx", 301: "3xx", 404: "4xx", 502: "5xx", 0: "none"} {
+		if c := StatusClass(status); c != expected {
+			t.Fatalf("expected %s for %d; received %s", expected, status, c)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	queueSize     = 1000
+	dialTimeout   = time.Second * 5
+	redialBackoff = time.Second * 5
+)
+
+// Forwarder writes the access log entries as JSON lines to a file or a
+// tcp sink
+type Forwarder struct {
+	target   string
+	open     func() (io.WriteCloser, error)
+	w        io.WriteCloser
+	nextOpen time.Time
+	queue    chan *Entry
+	flushReq chan chan bool
+}
+
+// NewForwarder returns a forwarder for the target (file:///path or
+// tcp://host:port)
+func NewForwarder(target string) (*Forwarder, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access log forward target: %s", err)
+	}
+
+	f := &Forwarder{
+		target:   target,
+		queue:    make(chan *Entry, queueSize),
+		flushReq: make(chan chan bool),
+	}
+
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("access log forward file must be specified: %s", target)
+		}
+
+		// open the file on start to fail early on invalid paths
+		w, err := os.OpenFile(u.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open access log file: %s", err)
+		}
+
+		f.w = w
+		f.open = func() (io.WriteCloser, error) {
+			return os.OpenFile(u.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		}
+	case "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("access log forward address must be specified: %s", target)
+		}
+
+		f.open = func() (io.WriteCloser, error) {
+			return net.DialTimeout("tcp", u.Host, dialTimeout)
+		}
+	default:
+		return nil, fmt.Errorf("unknown access log forward target: %s", target)
+	}
+
+	go f.run()
+
+	return f, nil
+}
+
+// Forward queues the entry.  Entries are dropped if the queue is full.
+func (f *Forwarder) Forward(e *Entry) {
+	select {
+	case f.queue <- e:
+	default:
+		log().Warnf("access log queue full; dropping entry: target=%s", f.target)
+	}
+}
+
+// Flush writes the queued entries
+func (f *Forwarder) Flush() {
+	done := make(chan bool)
+	f.flushReq <- done
+	<-done
+}
+
+func (f *Forwarder) run() {
+	for {
+		select {
+		case e := <-f.queue:
+			f.write(e)
+		case done := <-f.flushReq:
+			for n := len(f.queue); n > 0; n-- {
+				f.write(<-f.queue)
+			}
+			done <- true
+		}
+	}
+}
+
+// write writes the entry to the sink and reopens the sink on errors.
+// Entries are dropped while the sink is unavailable.
+func (f *Forwarder) write(e *Entry) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		log().Warnf("unable to encode access log entry: err=%s", err)
+		return
+	}
+
+	if f.w == nil {
+		if time.Now().Before(f.nextOpen) {
+			return
+		}
+
+		w, err := f.open()
+		if err != nil {
+			log().Warnf("unable to open access log sink: target=%s err=%s", f.target, err)
+			f.nextOpen = time.Now().Add(redialBackoff)
+			return
+		}
+
+		f.w = w
+	}
+
+	if _, err := f.w.Write(append(data, '\n')); err != nil {
+		log().Warnf("unable to forward access log entry: target=%s err=%s", f.target, err)
+		f.w.Close()
+		f.w = nil
+	}
+}
@@ -0,0 +1,98 @@
+package accesslog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestForwardFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "interlock-accesslog-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "access.json")
+	f, err := NewForwarder("file://" + p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Forward(&Entry{Domain: "example.com", Status: 200})
+	f.Forward(&Entry{Domain: "example.com", Status: 502})
+	f.Flush()
+
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var e *Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		lines++
+	}
+
+	if lines != 2 {
+		t.Fatalf("expected 2 entries; received %d", lines)
+	}
+}
+
+func TestForwardTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	entries := make(chan *Entry, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var e *Entry
+		if err := json.NewDecoder(conn).Decode(&e); err != nil {
+			t.Error(err)
+			return
+		}
+		entries <- e
+	}()
+
+	f, err := NewForwarder("tcp://" + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Forward(&Entry{Domain: "example.com", Path: "/"})
+
+	select {
+	case e := <-entries:
+		if e.Domain != "example.com" || e.Path != "/" {
+			t.Fatalf("unexpected entry: %+v", e)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timeout waiting for entry")
+	}
+}
+
+func TestNewForwarderInvalid(t *testing.T) {
+	for _, target := range []string{"udp://127.0.0.1:514", "tcp://", "file://"} {
+		if _, err := NewForwarder(target); err == nil {
+			t.Fatalf("expected error for %s", target)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package accesslog
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxMessageSize = 65536
+)
+
+var (
+	// rfc3164: <pri>timestamp [hostname] tag[pid]: content
+	syslogRegex = regexp.MustCompile(`^<(\d{1,3})>(?:[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2} )?(?:(\S+) )?([^\s:\[]+)(?:\[\d+\])?: (.*)$`)
+)
+
+// Message is a syslog message sent by a proxy container
+type Message struct {
+	Priority int
+	Hostname string
+	Tag      string
+	Content  string
+	Source   string // sender address
+}
+
+// ParseSyslog parses an rfc3164 syslog message as sent by nginx and
+// haproxy
+func ParseSyslog(data []byte) (*Message, error) {
+	msg := strings.TrimRight(string(data), "\r\n\x00")
+
+	m := syslogRegex.FindStringSubmatch(msg)
+	if m == nil {
+		return nil, fmt.Errorf("invalid syslog message: %q", msg)
+	}
+
+	pri, _ := strconv.Atoi(m[1])
+
+	return &Message{
+		Priority: pri,
+		Hostname: m[2],
+		Tag:      m[3],
+		Content:  m[4],
+	}, nil
+}
+
+// Receiver receives the syslog messages from the proxy containers over
+// udp
+type Receiver struct {
+	conn    net.PacketConn
+	handler func(m *Message)
+}
+
+// NewReceiver listens on the udp address and calls the handler for each
+// received message
+func NewReceiver(addr string, handler func(m *Message)) (*Receiver, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to start syslog receiver: %s", err)
+	}
+
+	r := &Receiver{
+		conn:    conn,
+		handler: handler,
+	}
+
+	go r.run()
+
+	return r, nil
+}
+
+// Addr returns the listen address of the receiver
+func (r *Receiver) Addr() net.Addr {
+	return r.conn.LocalAddr()
+}
+
+// Close stops the receiver
+func (r *Receiver) Close() error {
+	return r.conn.Close()
+}
+
+func (r *Receiver) run() {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			if strings.Contains(err.Error(), "use of closed network connection") {
+				return
+			}
+
+			log().Warnf("error reading syslog message: err=%s", err)
+			continue
+		}
+
+		m, err := ParseSyslog(buf[:n])
+		if err != nil {
+			log().Debug(err)
+			continue
+		}
+
+		if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+			m.Source = host
+		}
+
+		r.handler(m)
+	}
+}
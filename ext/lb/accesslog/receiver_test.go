@@ -0,0 +1,68 @@
+package accesslog
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseSyslog(t *testing.T) {
+	for data, expected := range map[string]*Message{
+		"<150>Oct 18 12:00:00 haproxy[12]: 10.0.0.10:52314 [18/Oct/2026:12:00:00.250] http-default\n": {
+			Priority: 150,
+			Tag:      "haproxy",
+			Content:  "10.0.0.10:52314 [18/Oct/2026:12:00:00.250] http-default",
+		},
+		"<190>Oct  8 12:00:00 4e1a5c2b9d10 nginx: app_example_com example.com 10.0.0.10": {
+			Priority: 190,
+			Hostname: "4e1a5c2b9d10",
+			Tag:      "nginx",
+			Content:  "app_example_com example.com 10.0.0.10",
+		},
+	} {
+		m, err := ParseSyslog([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if *m != *expected {
+			t.Fatalf("expected %+v; received %+v", expected, m)
+		}
+	}
+}
+
+func TestParseSyslogInvalid(t *testing.T) {
+	if _, err := ParseSyslog([]byte("GET / HTTP/1.1")); err == nil {
+		t.Fatal("expected error for invalid syslog message")
+	}
+}
+
+func TestReceiver(t *testing.T) {
+	messages := make(chan *Message, 1)
+	r, err := NewReceiver("127.0.0.1:0", func(m *Message) {
+		messages <- m
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	conn, err := net.Dial("udp", r.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("<190>Oct 18 12:00:00 proxy nginx: - example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case m := <-messages:
+		if m.Content != "- example.com" || m.Source != "127.0.0.1" {
+			t.Fatalf("unexpected message: %+v", m)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timeout waiting for message")
+	}
+}
@@ -0,0 +1,99 @@
+package lb
+
+import (
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ehazlett/interlock/ext/lb/certs"
+	"github.com/ehazlett/interlock/ext/lb/haproxy"
+	"github.com/ehazlett/interlock/ext/lb/nginx"
+	lbutils "github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+// proxyCerts returns the certificates referenced by the proxy config.  Certs
+// generated by interlock (bundles, secrets) are read from the proxy files;
+// all others are read from the local filesystem.
+func (l *LoadBalancer) proxyCerts(cfg interface{}) []*certs.Cert {
+	proxyCerts := []*certs.Cert{}
+	seen := map[string]*certs.Cert{}
+
+	addCert := func(name string, files []*lbutils.ProxyFile, domains ...string) {
+		if c, ok := seen[name]; ok {
+			c.Domains = append(c.Domains, domains...)
+			return
+		}
+
+		data, err := readProxyFile(name, l.cfg.ConfigBasePath, files)
+		if err != nil {
+			log().Warnf("unable to read ssl cert for monitoring: name=%s err=%s", name, err)
+		}
+
+		c := &certs.Cert{
+			Name:    name,
+			Data:    data,
+			Domains: domains,
+			Err:     err,
+		}
+		seen[name] = c
+		proxyCerts = append(proxyCerts, c)
+	}
+
+	switch config := cfg.(type) {
+	case *nginx.Config:
+		for _, h := range config.Hosts {
+			if !h.SSL || h.SSLCert == "" || h.ContextRoot.Path != "" {
+				continue
+			}
+
+			addCert(h.SSLCert, config.Files, h.ServerNames...)
+		}
+	case *haproxy.Config:
+		if config.Config.SSLCert != "" {
+			addCert(config.Config.SSLCert, config.Files)
+		}
+
+		for _, h := range config.Hosts {
+			if h.SSLCert == "" {
+				continue
+			}
+
+			addCert(path.Join(config.CertBundlePath, h.SSLCert), config.Files, h.Domain)
+		}
+	}
+
+	return proxyCerts
+}
+
+// readProxyFile returns the data for the file at the path in the proxy
+// container if generated by interlock or from the local filesystem
+func readProxyFile(p string, basePath string, files []*lbutils.ProxyFile) ([]byte, error) {
+	for _, f := range files {
+		if path.Join(basePath, f.Name) == p {
+			return f.Data, nil
+		}
+	}
+
+	return ioutil.ReadFile(filepath.Clean(p))
+}
+
+// checkCerts runs the certificate monitor against the last proxy config
+func (l *LoadBalancer) checkCerts() {
+	l.lock.Lock()
+	proxyCerts := l.certs
+	l.lock.Unlock()
+
+	if len(proxyCerts) == 0 {
+		return
+	}
+
+	statuses := certs.Monitor(l.backend.Name(), proxyCerts, l.cfg.SSLCertExpiryWarning)
+
+	names := []string{}
+	for _, s := range statuses {
+		names = append(names, s.Name)
+	}
+
+	log().Debugf("checked ssl certs: %s", strings.Join(names, ","))
+}
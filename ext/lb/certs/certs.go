@@ -0,0 +1,80 @@
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	pluginName = "certs"
+)
+
+// Cert is a certificate configured for one or more domains
+type Cert struct {
+	Name    string
+	Data    []byte
+	Domains []string
+	Err     error // set if the certificate could not be read
+}
+
+// Status is the result of checking a certificate
+type Status struct {
+	Name             string
+	NotAfter         time.Time
+	DaysUntilExpiry  float64
+	UncoveredDomains []string
+}
+
+func log() *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{
+		"ext": pluginName,
+	})
+}
+
+// ParseCertificate returns the first certificate in the pem data
+func ParseCertificate(data []byte) (*x509.Certificate, error) {
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+
+		data = rest
+	}
+
+	return nil, fmt.Errorf("no certificate found")
+}
+
+// CheckCert parses the certificate and returns the expiry as well as
+// any domains that are not covered by the certificate
+func CheckCert(c *Cert, now time.Time) (*Status, error) {
+	crt, err := ParseCertificate(c.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	uncovered := []string{}
+	for _, d := range c.Domains {
+		if err := crt.VerifyHostname(d); err != nil {
+			uncovered = append(uncovered, d)
+		}
+	}
+
+	days := crt.NotAfter.Sub(now).Hours() / 24
+
+	return &Status{
+		Name:             c.Name,
+		NotAfter:         crt.NotAfter,
+		DaysUntilExpiry:  math.Floor(days*100) / 100,
+		UncoveredDomains: uncovered,
+	}, nil
+}
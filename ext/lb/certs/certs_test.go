@@ -0,0 +1,109 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func generateCert(t *testing.T, notAfter time.Time, dnsNames ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: dnsNames[0],
+		},
+		DNSNames:  dnsNames,
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: der,
+	})
+}
+
+func TestCheckCert(t *testing.T) {
+	now := time.Now()
+	c := &Cert{
+		Name:    "example.com.pem",
+		Data:    generateCert(t, now.Add(time.Hour*24*10), "example.com", "*.example.com"),
+		Domains: []string{"example.com", "www.example.com", "example.org"},
+	}
+
+	s, err := CheckCert(c, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.DaysUntilExpiry < 9.9 || s.DaysUntilExpiry > 10 {
+		t.Fatalf("expected 10 days until expiry; received %f", s.DaysUntilExpiry)
+	}
+
+	if len(s.UncoveredDomains) != 1 || s.UncoveredDomains[0] != "example.org" {
+		t.Fatalf("expected example.org to be uncovered; received %v", s.UncoveredDomains)
+	}
+}
+
+func TestParseCertificateBundle(t *testing.T) {
+	keyBlock := pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: []byte("key"),
+	})
+
+	data := append(keyBlock, generateCert(t, time.Now().Add(time.Hour), "example.com")...)
+
+	crt, err := ParseCertificate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if crt.Subject.CommonName != "example.com" {
+		t.Fatalf("expected example.com; received %s", crt.Subject.CommonName)
+	}
+}
+
+func TestParseCertificateInvalid(t *testing.T) {
+	if _, err := ParseCertificate([]byte("invalid")); err == nil {
+		t.Fatal("expected error for invalid certificate")
+	}
+}
+
+func TestMonitorCheckError(t *testing.T) {
+	c := &Cert{
+		Name: "missing.pem",
+		Err:  fmt.Errorf("no such file"),
+	}
+
+	if statuses := Monitor("test", []*Cert{c}, 30); len(statuses) != 0 {
+		t.Fatalf("expected no statuses; received %d", len(statuses))
+	}
+
+	m := &dto.Metric{}
+	if err := certCheckError.WithLabelValues("test", "missing.pem").Write(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := m.GetGauge().GetValue(); v != 1 {
+		t.Fatalf("expected check error 1; received %f", v)
+	}
+}
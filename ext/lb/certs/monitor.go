@@ -0,0 +1,124 @@
+package certs
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	certExpiryDays = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "interlock",
+			Subsystem: "ssl",
+			Name:      "cert_expiry_days",
+			Help:      "Days until the certificate expires",
+		},
+		[]string{
+			"ext",
+			"cert",
+		},
+	)
+	certDomainMismatch = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "interlock",
+			Subsystem: "ssl",
+			Name:      "cert_domain_mismatch",
+			Help:      "Set to 1 if the domain is not covered by the certificate",
+		},
+		[]string{
+			"ext",
+			"cert",
+			"domain",
+		},
+	)
+	certCheckError = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "interlock",
+			Subsystem: "ssl",
+			Name:      "cert_check_error",
+			Help:      "Set to 1 if the certificate cannot be read or parsed",
+		},
+		[]string{
+			"ext",
+			"cert",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(certExpiryDays)
+	prometheus.MustRegister(certDomainMismatch)
+	prometheus.MustRegister(certCheckError)
+}
+
+// Monitor checks the configured certificates, updates the metrics and
+// warns if a certificate is close to expiry or does not cover its domains
+func Monitor(ext string, certs []*Cert, warnDays int) []*Status {
+	certExpiryDays.Reset()
+	certDomainMismatch.Reset()
+	certCheckError.Reset()
+
+	statuses := []*Status{}
+
+	for _, c := range certs {
+		checkError := certCheckError.With(prometheus.Labels{
+			"ext":  ext,
+			"cert": c.Name,
+		})
+
+		// read errors are logged when the cert is loaded
+		if c.Err != nil {
+			checkError.Set(1)
+			continue
+		}
+
+		s, err := CheckCert(c, time.Now())
+		if err != nil {
+			log().Errorf("unable to check ssl cert: name=%s err=%s", c.Name, err)
+			checkError.Set(1)
+			continue
+		}
+
+		checkError.Set(0)
+
+		certExpiryDays.With(prometheus.Labels{
+			"ext":  ext,
+			"cert": c.Name,
+		}).Set(s.DaysUntilExpiry)
+
+		switch {
+		case s.DaysUntilExpiry <= 0:
+			log().Errorf("ssl cert expired: name=%s expiry=%s", c.Name, s.NotAfter)
+		case s.DaysUntilExpiry <= float64(warnDays):
+			log().Warnf("ssl cert expires soon: name=%s expiry=%s days=%0.f", c.Name, s.NotAfter, s.DaysUntilExpiry)
+		default:
+			log().Debugf("ssl cert valid: name=%s expiry=%s", c.Name, s.NotAfter)
+		}
+
+		for _, d := range c.Domains {
+			v := 0.0
+			for _, u := range s.UncoveredDomains {
+				if u == d {
+					v = 1
+					break
+				}
+			}
+
+			certDomainMismatch.With(prometheus.Labels{
+				"ext":    ext,
+				"cert":   c.Name,
+				"domain": d,
+			}).Set(v)
+		}
+
+		if len(s.UncoveredDomains) > 0 {
+			log().Warnf("ssl cert does not cover domains: name=%s domains=%s", c.Name, strings.Join(s.UncoveredDomains, ","))
+		}
+
+		statuses = append(statuses, s)
+	}
+
+	return statuses
+}
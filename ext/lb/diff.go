@@ -0,0 +1,233 @@
+package lb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/ehazlett/interlock/ext"
+	"github.com/ehazlett/interlock/ext/lb/certs"
+	"github.com/ehazlett/interlock/ext/lb/haproxy"
+	"github.com/ehazlett/interlock/ext/lb/nginx"
+)
+
+const (
+	diffWebhookTimeout = time.Second * 10
+)
+
+// hostState is the routing state of a host in the proxy config
+type hostState struct {
+	Upstreams []string
+	TLS       string
+}
+
+// proxyState returns the routing state of the hosts in the proxy config
+// keyed by domain
+func (l *LoadBalancer) proxyState(cfg interface{}, proxyCerts []*certs.Cert) (map[string]*hostState, error) {
+	fingerprints := map[string]string{}
+	for _, c := range proxyCerts {
+		if c.Err != nil {
+			continue
+		}
+
+		fingerprints[c.Name] = fmt.Sprintf("%x", sha256.Sum256(c.Data))[:16]
+	}
+
+	tls := func(cert string, sslOnly bool) string {
+		if cert == "" {
+			return ""
+		}
+
+		s := cert
+		if fp, ok := fingerprints[cert]; ok {
+			s += " sha256:" + fp
+		}
+
+		if sslOnly {
+			s += " ssl_only"
+		}
+
+		return s
+	}
+
+	state := map[string]*hostState{}
+
+	switch config := cfg.(type) {
+	case *nginx.Config:
+		for _, h := range config.Hosts {
+			addrs := []string{}
+			for _, s := range h.Upstream.Servers {
+				addrs = append(addrs, s.Addr)
+			}
+
+			cert := ""
+			if h.SSL {
+				cert = h.SSLCert
+			}
+
+			state[h.Upstream.Name] = &hostState{
+				Upstreams: addrs,
+				TLS:       tls(cert, h.SSLOnly),
+			}
+		}
+	case *haproxy.Config:
+		for _, h := range config.Hosts {
+			addrs := []string{}
+			for _, up := range h.Upstreams {
+				if !up.Disabled {
+					addrs = append(addrs, up.Addr)
+				}
+			}
+
+			// hosts without a certificate use the default certificate
+			cert := config.Config.SSLCert
+			if h.SSLCert != "" {
+				cert = path.Join(config.CertBundlePath, h.SSLCert)
+			}
+
+			state[h.Domain] = &hostState{
+				Upstreams: addrs,
+				TLS:       tls(cert, h.SSLOnly),
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown backend type: %s", l.backend.Name())
+	}
+
+	return state, nil
+}
+
+// diffState returns the changes from the prev to the cur proxy state
+func diffState(prev, cur map[string]*hostState) *ext.ConfigDiff {
+	d := &ext.ConfigDiff{
+		HostsAdded:       []string{},
+		HostsRemoved:     []string{},
+		UpstreamsAdded:   map[string][]string{},
+		UpstreamsRemoved: map[string][]string{},
+		TLSChanged:       []*ext.TLSChange{},
+	}
+
+	for _, domain := range sortedDomains(cur) {
+		h := cur[domain]
+		p, ok := prev[domain]
+		if !ok {
+			d.HostsAdded = append(d.HostsAdded, domain)
+			p = &hostState{}
+		}
+
+		if added := difference(h.Upstreams, p.Upstreams); len(added) > 0 {
+			d.UpstreamsAdded[domain] = added
+		}
+
+		if removed := difference(p.Upstreams, h.Upstreams); len(removed) > 0 {
+			d.UpstreamsRemoved[domain] = removed
+		}
+
+		if ok && p.TLS != h.TLS {
+			d.TLSChanged = append(d.TLSChanged, &ext.TLSChange{
+				Host:   domain,
+				Before: p.TLS,
+				After:  h.TLS,
+			})
+		}
+	}
+
+	for _, domain := range sortedDomains(prev) {
+		if _, ok := cur[domain]; ok {
+			continue
+		}
+
+		d.HostsRemoved = append(d.HostsRemoved, domain)
+		if len(prev[domain].Upstreams) > 0 {
+			d.UpstreamsRemoved[domain] = prev[domain].Upstreams
+		}
+	}
+
+	return d
+}
+
+// diffSummary returns a one line summary of the diff
+func diffSummary(cur map[string]*hostState, d *ext.ConfigDiff) string {
+	upsAdded, upsRemoved := 0, 0
+	for _, addrs := range d.UpstreamsAdded {
+		upsAdded += len(addrs)
+	}
+	for _, addrs := range d.UpstreamsRemoved {
+		upsRemoved += len(addrs)
+	}
+
+	return fmt.Sprintf("hosts=%d (+%d -%d) upstreams=+%d -%d tls=%d", len(cur), len(d.HostsAdded), len(d.HostsRemoved), upsAdded, upsRemoved, len(d.TLSChanged))
+}
+
+func sortedDomains(state map[string]*hostState) []string {
+	domains := []string{}
+	for d := range state {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+
+	return domains
+}
+
+// difference returns the values in a that are not in b
+func difference(a, b []string) []string {
+	d := []string{}
+	for _, v := range a {
+		if !contains(b, v) {
+			d = append(d, v)
+		}
+	}
+
+	sort.Strings(d)
+
+	return d
+}
+
+// logDiff logs the config changes of the reload
+func logDiff(r *ext.Reload) {
+	if r.Diff == nil || r.Diff.Empty() {
+		return
+	}
+
+	for _, line := range r.Diff.Lines() {
+		log().WithFields(logrus.Fields{
+			"reload_id": r.ID,
+			"trigger":   r.Trigger,
+		}).Infof("proxy config change: %s", line)
+	}
+}
+
+// postDiff posts the reload record to the diff webhook if the config
+// changed
+func postDiff(url string, r *ext.Reload) {
+	if r.Diff == nil || r.Diff.Empty() {
+		return
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		log().WithField("reload_id", r.ID).Errorf("unable to encode config diff: %s", err)
+		return
+	}
+
+	client := &http.Client{
+		Timeout: diffWebhookTimeout,
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		log().WithField("reload_id", r.ID).Warnf("unable to post config diff: url=%s err=%s", url, err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		log().WithField("reload_id", r.ID).Warnf("unable to post config diff: url=%s status=%d", url, resp.StatusCode)
+	}
+}
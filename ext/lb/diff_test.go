@@ -0,0 +1,115 @@
+package lb
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ehazlett/interlock/ext"
+)
+
+func testState() map[string]*hostState {
+	return map[string]*hostState{
+		"example.com": {
+			Upstreams: []string{"10.0.0.1:80", "10.0.0.2:80"},
+			TLS:       "/certs/example.com.pem sha256:0123456789abcdef",
+		},
+		"old.com": {
+			Upstreams: []string{"10.0.0.3:80"},
+		},
+	}
+}
+
+func TestDiffState(t *testing.T) {
+	prev := testState()
+	cur := map[string]*hostState{
+		"example.com": {
+			Upstreams: []string{"10.0.0.1:80", "10.0.0.4:80"},
+			TLS:       "/certs/example.com.pem sha256:fedcba9876543210",
+		},
+		"new.com": {
+			Upstreams: []string{"10.0.0.5:80"},
+		},
+	}
+
+	d := diffState(prev, cur)
+
+	if len(d.HostsAdded) != 1 || d.HostsAdded[0] != "new.com" {
+		t.Fatalf("expected new.com added; received %v", d.HostsAdded)
+	}
+
+	if len(d.HostsRemoved) != 1 || d.HostsRemoved[0] != "old.com" {
+		t.Fatalf("expected old.com removed; received %v", d.HostsRemoved)
+	}
+
+	if addrs := d.UpstreamsAdded["example.com"]; len(addrs) != 1 || addrs[0] != "10.0.0.4:80" {
+		t.Fatalf("expected 10.0.0.4:80 added to example.com; received %v", addrs)
+	}
+
+	if addrs := d.UpstreamsRemoved["example.com"]; len(addrs) != 1 || addrs[0] != "10.0.0.2:80" {
+		t.Fatalf("expected 10.0.0.2:80 removed from example.com; received %v", addrs)
+	}
+
+	if addrs := d.UpstreamsRemoved["old.com"]; len(addrs) != 1 {
+		t.Fatalf("expected old.com upstreams removed; received %v", addrs)
+	}
+
+	if len(d.TLSChanged) != 1 || d.TLSChanged[0].Host != "example.com" {
+		t.Fatalf("expected example.com tls change; received %v", d.TLSChanged)
+	}
+
+	expected := "hosts=2 (+1 -1) upstreams=+2 -2 tls=1"
+	if s := diffSummary(cur, d); s != expected {
+		t.Fatalf("expected summary %q; received %q", expected, s)
+	}
+
+	lines := d.Lines()
+	if len(lines) != 7 {
+		t.Fatalf("expected 7 changes; received %v", lines)
+	}
+
+	if lines[0] != "+ host new.com" {
+		t.Fatalf("expected host added first; received %q", lines[0])
+	}
+}
+
+func TestDiffStateNoChanges(t *testing.T) {
+	d := diffState(testState(), testState())
+	if !d.Empty() {
+		t.Fatalf("expected empty diff; received %v", d.Lines())
+	}
+
+	if s := d.String(); s != "no changes" {
+		t.Fatalf("expected no changes; received %q", s)
+	}
+}
+
+func TestPostDiff(t *testing.T) {
+	received := make(chan *ext.Reload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rec *ext.Reload
+		if err := json.NewDecoder(r.Body).Decode(&rec); err != nil {
+			t.Error(err)
+		}
+		received <- rec
+	}))
+	defer srv.Close()
+
+	r := &ext.Reload{
+		Ext:     "nginx",
+		Trigger: "start",
+		Diff:    diffState(map[string]*hostState{}, testState()),
+	}
+
+	postDiff(srv.URL, r)
+
+	rec := <-received
+	if rec.Trigger != "start" {
+		t.Fatalf("expected start trigger; received %s", rec.Trigger)
+	}
+
+	if len(rec.Diff.HostsAdded) != 2 {
+		t.Fatalf("expected 2 hosts added; received %v", rec.Diff.HostsAdded)
+	}
+}
@@ -0,0 +1,114 @@
+package lb
+
+import (
+	"time"
+
+	"github.com/docker/engine-api/types"
+	"golang.org/x/net/context"
+)
+
+const (
+	drainPollInterval = time.Second * 1
+)
+
+// Drainer is implemented by backends that can drain upstream connections
+// through the proxy runtime api
+type Drainer interface {
+	// Drain stops new connections to the container on the proxy
+	Drain(proxyAddr string, cInfo types.ContainerJSON) error
+	// Connections returns the current connections to the container on
+	// the proxy
+	Connections(proxyAddr string, cInfo types.ContainerJSON) (int, error)
+}
+
+// drain marks the container as draining on the proxies and waits for
+// the connections to finish or the drain timeout before reloading to
+// remove it from the proxy config.  The config generation renders draining
+// containers without new connections and leaves drained containers out.
+func (l *LoadBalancer) drain(id string) {
+	drainer, ok := l.backend.(Drainer)
+	if !ok || l.cfg.RuntimeAPIPort == 0 {
+		return
+	}
+
+	timeout, err := time.ParseDuration(l.cfg.DrainTimeout)
+	if err != nil {
+		log().Errorf("unable to parse drain timeout: %s", err)
+		return
+	}
+
+	if !l.drains.Start(id) {
+		return
+	}
+
+	go func() {
+		defer func() {
+			l.drains.Done(id)
+
+			if l.runtimeUpdate(context.Background(), id, false) {
+				log().WithField("container_id", id).Debug("drain complete; removed through runtime api")
+				return
+			}
+
+			log().WithField("container_id", id).Debug("drain complete; triggering reload")
+			l.triggerReload(context.Background(), "drain")
+		}()
+
+		cInfo, err := l.client.ContainerInspect(context.Background(), id)
+		if err != nil {
+			log().WithField("container_id", id).Errorf("unable to inspect container for drain: err=%s", err)
+			return
+		}
+
+		proxyContainers, err := l.ProxyContainers(l.backend.Name())
+		if err != nil {
+			log().Errorf("unable to get proxy containers for drain: %s", err)
+			return
+		}
+
+		addrs := []string{}
+		for _, cnt := range proxyContainers {
+			addr, err := l.proxyRuntimeAddr(cnt)
+			if err != nil {
+				log().Warn(err)
+				continue
+			}
+
+			if err := drainer.Drain(addr, cInfo); err != nil {
+				log().WithField("container_id", id).Warnf("unable to drain container: proxy=%s err=%s", addr, err)
+				continue
+			}
+
+			addrs = append(addrs, addr)
+		}
+
+		if len(addrs) == 0 {
+			return
+		}
+
+		log().WithField("container_id", id).Infof("draining connections: timeout=%s", timeout)
+
+		deadline := time.Now().Add(timeout)
+		for time.Now().Before(deadline) {
+			conns := 0
+			for _, addr := range addrs {
+				n, err := drainer.Connections(addr, cInfo)
+				if err != nil {
+					log().WithField("container_id", id).Warnf("unable to get connections: proxy=%s err=%s", addr, err)
+					continue
+				}
+				conns += n
+			}
+
+			if conns == 0 {
+				log().WithField("container_id", id).Info("connections drained")
+				return
+			}
+
+			log().WithField("container_id", id).Debugf("waiting for connections to drain: connections=%d", conns)
+			time.Sleep(drainPollInterval)
+		}
+
+		log().WithField("container_id", id).Warn("drain timeout reached; removing container")
+	}()
+}
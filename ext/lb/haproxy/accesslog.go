@@ -0,0 +1,18 @@
+package haproxy
+
+import (
+	"github.com/ehazlett/interlock/ext/lb/accesslog"
+)
+
+// ParseAccessLog parses an access log line sent by the proxy and maps the
+// backend to the interlock domain
+func (p *HAProxyLoadBalancer) ParseAccessLog(line string) (*accesslog.Entry, error) {
+	e, err := accesslog.ParseHAProxy(line)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Domain = p.labels.Domain(e.Upstream)
+
+	return e, nil
+}
@@ -0,0 +1,32 @@
+package haproxy
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+// basicAuthUsers returns the users for the haproxy userlist from the
+// htpasswd in the secret store or BasicAuthPath
+func (p *HAProxyLoadBalancer) basicAuthUsers(src string, secret bool) ([]*utils.AuthUser, error) {
+	var data []byte
+	var err error
+
+	if secret {
+		data, err = p.secret(src)
+	} else {
+		var f string
+		f, err = utils.ConfinedPath(p.cfg.BasicAuthPath, src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid htpasswd file (BasicAuthPath): %s", err)
+		}
+
+		data, err = ioutil.ReadFile(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.ParseHtpasswd(data)
+}
@@ -0,0 +1,76 @@
+package haproxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/ehazlett/interlock/ext/lb/secrets"
+	"github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+const (
+	certBundleDir = "certs"
+
+	// bundles from the ssl cert path and the secret store share the
+	// bundle dir; the prefixes keep their names apart
+	certBundlePrefix   = "cert_"
+	secretBundlePrefix = "secret_"
+)
+
+// certBundleName returns the name of the combined pem bundle for the
+// specified certificate and key.  the base name is kept for readability;
+// the hash of both paths keeps certs with the same base name or with
+// different keys apart
+func certBundleName(certName, keyName string) string {
+	name := strings.TrimSuffix(filepath.Base(certName), filepath.Ext(certName))
+	sum := sha256.Sum256([]byte(certName + "\x00" + keyName))
+	return fmt.Sprintf("%s%s_%s.pem", certBundlePrefix, name, hex.EncodeToString(sum[:4]))
+}
+
+// secretBundleName returns the name of the pem bundle for the specified
+// secret key
+func secretBundleName(key string) string {
+	return secretBundlePrefix + secrets.FileName(key)
+}
+
+// certBundle reads the certificate and key from the ssl cert path and
+// returns a combined pem bundle as expected by haproxy
+func (p *HAProxyLoadBalancer) certBundle(certName, keyName string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	for _, n := range []string{certName, keyName} {
+		if n == "" {
+			continue
+		}
+
+		certPath, err := utils.ConfinedPath(p.cfg.SSLCertPath, n)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ssl cert (SSLCertPath): %s", err)
+		}
+
+		d, err := ioutil.ReadFile(certPath)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(bytes.TrimSpace(d))
+		buf.WriteString("\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// secret returns the data for the specified key from the
+// secret store
+func (p *HAProxyLoadBalancer) secret(key string) ([]byte, error) {
+	if p.secrets == nil {
+		return nil, fmt.Errorf("no secret store configured")
+	}
+
+	return p.secrets.Get(key)
+}
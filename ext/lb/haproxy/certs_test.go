@@ -0,0 +1,115 @@
+package haproxy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ehazlett/interlock/config"
+)
+
+func TestCertBundleName(t *testing.T) {
+	name := certBundleName("example.com.crt", "example.com.key")
+
+	if !strings.HasPrefix(name, "cert_example.com_") || !strings.HasSuffix(name, ".pem") {
+		t.Fatalf("expected cert_example.com_<hash>.pem; received %s", name)
+	}
+
+	if name != certBundleName("example.com.crt", "example.com.key") {
+		t.Fatal("expected bundle name to be stable")
+	}
+}
+
+func TestCertBundleNameCollision(t *testing.T) {
+	names := map[string]bool{}
+
+	for _, c := range [][2]string{
+		{"a/site.crt", "a/site.key"},
+		{"b/site.pem", "a/site.key"},
+		{"a/site.crt", "b/site.key"},
+		{"a/site.crt", ""},
+	} {
+		name := certBundleName(c[0], c[1])
+		if names[name] {
+			t.Fatalf("expected unique bundle name for %s and %s; received %s", c[0], c[1], name)
+		}
+
+		names[name] = true
+	}
+}
+
+func TestSecretBundleName(t *testing.T) {
+	// a secret and a cert with the same name must not collide
+	if secretBundleName("example.com") == certBundleName("example.com.crt", "") {
+		t.Fatal("expected secret and cert bundle names to differ")
+	}
+
+	name := secretBundleName("/certs/example.com")
+
+	if !strings.HasPrefix(name, "secret_certs_example.com_") || !strings.HasSuffix(name, ".pem") {
+		t.Fatalf("expected secret_certs_example.com_<hash>.pem; received %s", name)
+	}
+}
+
+func TestCertBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "interlock-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "cert.pem"), []byte("cert\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "key.pem"), []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &HAProxyLoadBalancer{
+		cfg: &config.ExtensionConfig{
+			SSLCertPath: dir,
+		},
+	}
+
+	bundle, err := p.certBundle("cert.pem", "key.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "cert\nkey\n"
+	if string(bundle) != expected {
+		t.Fatalf("expected %q; received %q", expected, string(bundle))
+	}
+}
+
+func TestCertBundleOutsideCertPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "interlock-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certDir := filepath.Join(dir, "certs")
+	if err := os.Mkdir(certDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "secret.pem"), []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &HAProxyLoadBalancer{
+		cfg: &config.ExtensionConfig{
+			SSLCertPath: certDir,
+		},
+	}
+
+	for _, n := range []string{"../secret.pem", filepath.Join(dir, "secret.pem")} {
+		if _, err := p.certBundle(n, ""); err == nil {
+			t.Fatalf("expected error for cert %s outside of the ssl cert path", n)
+		}
+	}
+}
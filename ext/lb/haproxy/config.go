@@ -2,6 +2,7 @@ package haproxy
 
 import (
 	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext/lb/utils"
 )
 
 type ContextRoot struct {
@@ -15,22 +16,49 @@ type Host struct {
 	ContextRootRewrite  bool
 	Domain              string
 	Check               string
+	HealthCheck         *utils.HealthCheckSpec
 	BackendOptions      []string
 	Upstreams           []*Upstream
 	SSLOnly             bool
 	SSLBackend          bool
 	SSLBackendTLSVerify string
+	SSLCert             string
 	BalanceAlgorithm    string
+	StickyCookie        string
+	RateLimit           int
+	RateLimitPeriod     string
+	ConnLimit           int
+	LimitKey            string
+	LimitKeyType        string
+	Allow               []string
+	Deny                []string
+	BasicAuthUsers      []*utils.AuthUser
+	RequestHeaders      []*utils.Header
+	ResponseHeaders     []*utils.Header
+	HTTP2               bool
+	ServerProto         string
+	ServerALPN          string
+	Maintenance         bool
+	ErrorPages          []*utils.ErrorPage
 }
 
 type Upstream struct {
+	ID            string
 	Container     string
 	Addr          string
 	CheckInterval int
+	Cookie        string
+	Drain         bool
+	Disabled      bool
 }
 
 type Config struct {
-	Hosts    []*Host
-	Config   *config.ExtensionConfig
-	Networks map[string]string
+	Hosts          []*Host
+	Config         *config.ExtensionConfig
+	Networks       map[string]string
+	CertBundlePath string
+	Files          []*utils.ProxyFile
+	HTTP2          bool
+	Routes         []*utils.Route
+	RuntimeSocket  string
 }
@@ -0,0 +1,50 @@
+package haproxy
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"path"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+const (
+	errorPagesDir = "errors"
+)
+
+// errorPages returns the custom error pages for the domain and adds the
+// pages to the files copied to the proxy containers
+func (p *HAProxyLoadBalancer) errorPages(config *ctypes.Config, domain string, files map[string]*utils.ProxyFile) ([]*utils.ErrorPage, error) {
+	pages, err := utils.ErrorPages(config, domain, p.cfg.ErrorPagesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, page := range pages {
+		name := path.Join(errorPagesDir, domain, fmt.Sprintf("%d.http", page.Code))
+		files[name] = &utils.ProxyFile{
+			Name: name,
+			Mode: 0644,
+			Data: errorFile(page.Code, page.Data),
+		}
+		page.Path = path.Join(p.cfg.ConfigBasePath, name)
+	}
+
+	return pages, nil
+}
+
+// errorFile returns the page as a raw http response as haproxy sends
+// error files as is
+func errorFile(code int, body []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "HTTP/1.0 %d %s\r\n", code, http.StatusText(code))
+	b.WriteString("Cache-Control: no-cache\r\n")
+	b.WriteString("Connection: close\r\n")
+	b.WriteString("Content-Type: text/html\r\n")
+	b.WriteString("\r\n")
+	b.Write(body)
+
+	return b.Bytes()
+}
@@ -0,0 +1,18 @@
+package haproxy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestErrorFile(t *testing.T) {
+	data := string(errorFile(503, []byte("<h1>maintenance</h1>")))
+
+	if !strings.HasPrefix(data, "HTTP/1.0 503 Service Unavailable\r\n") {
+		t.Fatalf("unexpected status line: %q", data)
+	}
+
+	if !strings.HasSuffix(data, "\r\n\r\n<h1>maintenance</h1>") {
+		t.Fatalf("unexpected body: %q", data)
+	}
+}
@@ -2,9 +2,12 @@ package haproxy
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/ext/lb/health"
 	"github.com/ehazlett/interlock/ext/lb/utils"
 	"golang.org/x/net/context"
 )
@@ -14,15 +17,41 @@ func (p *HAProxyLoadBalancer) GenerateProxyConfig(containers []types.Container)
 
 	proxyUpstreams := map[string][]*Upstream{}
 	hostChecks := map[string]string{}
+	hostHealthChecks := map[string]*utils.HealthCheckSpec{}
 	hostBalanceAlgorithms := map[string]string{}
+	hostStickyCookie := map[string]string{}
 	hostContextRoots := map[string]*ContextRoot{}
 	hostContextRootRewrites := map[string]bool{}
 	hostBackendOptions := map[string][]string{}
 	hostSSLOnly := map[string]bool{}
 	hostSSLBackend := map[string]bool{}
 	hostSSLBackendTLSVerify := map[string]string{}
+	hostSSLCert := map[string]string{}
+	hostRateLimit := map[string]*utils.RateLimit{}
+	hostConnLimit := map[string]int{}
+	hostLimitKeyHeader := map[string]string{}
+	hostAllow := map[string][]string{}
+	hostDeny := map[string][]string{}
+	hostBasicAuthUsers := map[string][]*utils.AuthUser{}
+	hostRequestHeaders := map[string][]*utils.Header{}
+	hostResponseHeaders := map[string][]*utils.Header{}
+	hostProtocol := map[string]string{}
+	hostErrorPages := map[string][]*utils.ErrorPage{}
+	certBundles := map[string][]byte{}
+	proxyFiles := map[string]*utils.ProxyFile{}
+
+	// failing upstreams are probed again below
+	p.prober.Reset()
+
+	// domains in maintenance from the management api and labels
+	maintenanceDomains := map[string]bool{}
+	for _, d := range p.maintenance.Domains() {
+		maintenanceDomains[d] = true
+	}
 
 	networks := map[string]string{}
+	staticRoutes := []*utils.Route{}
+	authFailed := map[string]bool{}
 
 	for _, cnt := range containers {
 		cntId := cnt.ID[:12]
@@ -32,6 +61,12 @@ func (p *HAProxyLoadBalancer) GenerateProxyConfig(containers []types.Container)
 			return nil, err
 		}
 
+		// drained containers are removed from the config until they stop
+		if p.drains.Drained(cInfo.ID) {
+			p.reloadLog().WithField("container_id", cntId).Debug("container drained; excluding")
+			continue
+		}
+
 		hostname := utils.Hostname(cInfo.Config)
 		domain := utils.Domain(cInfo.Config)
 
@@ -53,6 +88,38 @@ func (p *HAProxyLoadBalancer) GenerateProxyConfig(containers []types.Container)
 			}
 		}
 
+		// marker containers for redirects and static responses
+		route, err := utils.StaticRoute(cInfo.Config, domain)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing route: %s", err)
+			continue
+		}
+
+		if route != nil {
+			if cInfo.State != nil && cInfo.State.Running && contextRoot == "" {
+				p.reloadLog().WithField("domain", domain).Infof("route: redirect=%s code=%d", route.Redirect, route.Code)
+				staticRoutes = append(staticRoutes, route)
+			}
+			continue
+		}
+
+		if contextRoot == "" {
+			if utils.MaintenanceEnabled(cInfo.Config) && cInfo.State != nil && cInfo.State.Running {
+				p.reloadLog().WithField("domain", domain).Info("maintenance mode enabled")
+				maintenanceDomains[domain] = true
+			}
+
+			// custom error pages
+			if _, ok := hostErrorPages[domain]; !ok {
+				pages, err := p.errorPages(cInfo.Config, domain, proxyFiles)
+				if err != nil {
+					p.reloadLog().WithField("domain", domain).Errorf("error loading error pages: %s", err)
+				} else {
+					hostErrorPages[domain] = pages
+				}
+			}
+		}
+
 		hostContextRoots[domain] = &ContextRoot{
 			Name: contextRootName,
 			Path: contextRoot,
@@ -62,7 +129,7 @@ func (p *HAProxyLoadBalancer) GenerateProxyConfig(containers []types.Container)
 		healthCheck := utils.HealthCheck(cInfo.Config)
 		healthCheckInterval, err := utils.HealthCheckInterval(cInfo.Config)
 		if err != nil {
-			log().Errorf("error parsing health check interval: %s", err)
+			p.reloadLog().Errorf("error parsing health check interval: %s", err)
 			continue
 		}
 
@@ -70,107 +137,283 @@ func (p *HAProxyLoadBalancer) GenerateProxyConfig(containers []types.Container)
 			if val, ok := hostChecks[domain]; ok {
 				// check existing host check for different values
 				if val != healthCheck {
-					log().Warnf("conflicting check specified for %s", domain)
+					p.reloadLog().WithField("domain", domain).Warn("conflicting check specified")
 				}
 			} else {
 				hostChecks[domain] = healthCheck
-				log().Debugf("using custom check for %s: %s", domain, healthCheck)
+				p.reloadLog().WithField("domain", domain).Debugf("using custom check: %s", healthCheck)
 			}
 
-			log().Debugf("check interval for %s: %d", domain, healthCheckInterval)
+			p.reloadLog().WithField("domain", domain).Debugf("check interval: %d", healthCheckInterval)
+		}
+
+		healthCheckSpec, err := utils.HealthCheckConfig(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing health check: %s", err)
+			continue
+		}
+		if healthCheckSpec != nil {
+			hostHealthChecks[domain] = healthCheckSpec
+		}
+
+		// the haproxy specific check takes precedence
+		if healthCheck == "" && healthCheckSpec.HTTP() {
+			if _, ok := hostChecks[domain]; !ok {
+				hostChecks[domain] = fmt.Sprintf("httpchk GET %s", healthCheckSpec.Path)
+			}
 		}
 
 		hostBalanceAlgorithms[domain] = utils.BalanceAlgorithm(cInfo.Config)
 
+		// sticky sessions
+		stickyCookie, err := utils.StickyCookie(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing sticky sessions: %s", err)
+		} else if stickyCookie != "" {
+			hostStickyCookie[domain] = stickyCookie
+		}
+
 		backendOptions := utils.BackendOptions(cInfo.Config)
 
 		if len(backendOptions) > 0 {
 			hostBackendOptions[domain] = backendOptions
-			log().Debugf("using backend options for %s: %s", domain, strings.Join(backendOptions, ","))
+			p.reloadLog().WithField("domain", domain).Debugf("using backend options: %s", strings.Join(backendOptions, ","))
 		}
 
 		hostSSLOnly[domain] = utils.SSLOnly(cInfo.Config)
 
-		// ssl backend
-		hostSSLBackend[domain] = utils.SSLBackend(cInfo.Config)
-		hostSSLBackendTLSVerify[domain] = utils.SSLBackendTLSVerify(cInfo.Config)
+		// rate and connection limits
+		rateLimit, err := utils.RateLimitConfig(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing rate limit: %s", err)
+		} else if rateLimit != nil {
+			hostRateLimit[domain] = rateLimit
+		}
+
+		connLimit, err := utils.ConnLimit(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing connection limit: %s", err)
+		} else if connLimit > 0 {
+			hostConnLimit[domain] = connLimit
+		}
 
-		addr := ""
+		hostLimitKeyHeader[domain] = utils.LimitKeyHeader(cInfo.Config)
 
-		// check for networking
-		if n, ok := utils.OverlayEnabled(cInfo.Config); ok {
-			log().Debugf("configuring docker network: name=%s", n)
+		// upstream protocol
+		protocol, err := utils.Protocol(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing protocol: %s", err)
+			continue
+		}
+		hostProtocol[domain] = protocol
 
-			// FIXME: for some reason the request from dockerclient
-			// is not returning a populated Networks object
-			// so we hack this by inspecting the Network
-			// we should switch to engine-api/client -- hopefully
-			// that will fix
-			//net, found := cInfo.NetworkSettings.Networks[n]
-			//if !found {
-			//	log().Errorf("container %s is not connected to the network %s", cInfo.Id, n)
-			//	continue
-			//}
+		// custom headers
+		requestHeaders, err := utils.RequestHeaders(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing request headers: %s", err)
+		} else {
+			hostRequestHeaders[domain] = requestHeaders
+		}
 
-			network, err := p.client.NetworkInspect(context.Background(), n)
+		responseHeaders, err := utils.ResponseHeaders(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing response headers: %s", err)
+		} else {
+			hostResponseHeaders[domain] = responseHeaders
+		}
+
+		// access control
+		allow, err := utils.AllowList(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing allow list: %s", err)
+			continue
+		}
+		hostAllow[domain] = utils.MergeAddressLists(hostAllow[domain], allow)
+
+		deny, err := utils.DenyList(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing deny list: %s", err)
+			continue
+		}
+		hostDeny[domain] = utils.MergeAddressLists(hostDeny[domain], deny)
+
+		if src, secret := utils.BasicAuth(cInfo.Config); src != "" {
+			users, err := p.basicAuthUsers(src, secret)
 			if err != nil {
-				log().Error(err)
+				// do not expose the service without auth
+				p.reloadLog().WithField("domain", domain).Errorf("error loading basic auth: %s", err)
+				authFailed[domain] = true
+				for _, alias := range utils.AliasDomains(cInfo.Config) {
+					authFailed[alias] = true
+				}
 				continue
 			}
 
-			addr, err = utils.BackendOverlayAddress(network, cInfo)
-			if err != nil {
-				log().Error(err)
-				continue
+			hostBasicAuthUsers[domain] = users
+		}
+
+		// ssl backend (http/2 over tls upstreams always use ssl)
+		hostSSLBackend[domain] = utils.SSLBackend(cInfo.Config) || protocol == utils.ProtocolH2
+		hostSSLBackendTLSVerify[domain] = utils.SSLBackendTLSVerify(cInfo.Config)
+
+		// ssl cert bundles for sni
+		if certName := utils.SSLCertName(cInfo.Config); certName != "" {
+			keyName := utils.SSLCertKey(cInfo.Config)
+			bundleName := certBundleName(certName, keyName)
+			if _, ok := certBundles[bundleName]; !ok {
+				bundle, err := p.certBundle(certName, keyName)
+				if err != nil {
+					p.reloadLog().WithField("domain", domain).Errorf("error loading ssl cert: %s", err)
+				} else {
+					p.reloadLog().WithField("domain", domain).Infof("ssl cert: %s", bundleName)
+					certBundles[bundleName] = bundle
+				}
 			}
 
-			networks[n] = ""
-		} else {
-			portsExposed := false
-			for _, portBindings := range cInfo.NetworkSettings.Ports {
-				if len(portBindings) != 0 {
-					portsExposed = true
-					break
+			if _, ok := certBundles[bundleName]; ok {
+				hostSSLCert[domain] = bundleName
+			}
+		}
+
+		// ssl secrets take precedence over certs from the ssl cert path
+		if secretKey := utils.SSLSecret(cInfo.Config); secretKey != "" {
+			bundleName := secretBundleName(secretKey)
+			if _, ok := certBundles[bundleName]; !ok {
+				bundle, err := p.secret(secretKey)
+				if err != nil {
+					p.reloadLog().WithField("domain", domain).Errorf("error loading ssl secret: %s", err)
+				} else {
+					p.reloadLog().WithField("domain", domain).Infof("ssl secret: %s", secretKey)
+					certBundles[bundleName] = bundle
 				}
 			}
-			if !portsExposed {
-				log().Warnf("%s: no ports exposed", cntId)
-				continue
+
+			if _, ok := certBundles[bundleName]; ok {
+				hostSSLCert[domain] = bundleName
 			}
+		}
 
-			addr, err = utils.BackendAddress(cInfo, p.cfg.BackendOverrideAddress)
-			if err != nil {
-				log().Error(err)
-				continue
+		addr, network, err := p.backendAddr(cInfo)
+		if err != nil {
+			if err == errNoPortsExposed {
+				p.reloadLog().WithField("container_id", cntId).Warn("no ports exposed")
+			} else {
+				p.reloadLog().Error(err)
 			}
+			continue
+		}
+
+		if network != "" {
+			networks[network] = ""
+		}
+
+		// exclude containers that do not report healthy
+		if p.cfg.RequireHealthy && !health.DockerHealthy(cInfo.State) {
+			p.reloadLog().WithField("container_id", cntId).Infof("container not healthy; excluding: status=%s", health.DockerStatus(cInfo.State))
+			continue
+		}
+
+		// exclude upstreams that fail the probe
+		if !p.prober.Healthy(&health.Target{
+			ID:     cInfo.ID,
+			Addr:   addr,
+			SSL:    hostSSLBackend[domain],
+			Check:  healthCheckSpec,
+			Status: health.DockerStatus(cInfo.State),
+		}) {
+			continue
 		}
 
 		container_name := cInfo.Name[1:]
 		up := &Upstream{
+			ID:            cInfo.ID,
 			Addr:          addr,
 			Container:     container_name,
 			CheckInterval: healthCheckInterval,
+			Cookie:        utils.StickyCookieValue(container_name),
+			Drain:         utils.Drain(cInfo.Config) || p.drains.Draining(cInfo.ID),
 		}
 
-		log().Infof("%s: upstream=%s container=%s", domain, addr, container_name)
+		p.reloadLog().WithFields(logrus.Fields{
+			"domain":       domain,
+			"container_id": cntId,
+			"container":    container_name,
+			"upstream":     addr,
+		}).Info("adding upstream")
 
 		// "parse" multiple labels for alias domains
 		aliasDomains := utils.AliasDomains(cInfo.Config)
 
-		log().Debugf("alias domains: %v", aliasDomains)
+		p.reloadLog().Debugf("alias domains: %v", aliasDomains)
 
 		for _, alias := range aliasDomains {
-			log().Debugf("adding alias %s for %s", alias, cntId)
+			p.reloadLog().WithField("container_id", cntId).Debugf("adding alias %s", alias)
 			proxyUpstreams[alias] = append(proxyUpstreams[alias], up)
 			hostContextRoots[alias] = &ContextRoot{
 				Name: contextRootName,
 				Path: contextRoot,
 			}
+			if cert, ok := hostSSLCert[domain]; ok {
+				hostSSLCert[alias] = cert
+			}
+			if r, ok := hostRateLimit[domain]; ok {
+				hostRateLimit[alias] = r
+			}
+			hostConnLimit[alias] = hostConnLimit[domain]
+			hostLimitKeyHeader[alias] = hostLimitKeyHeader[domain]
+			hostAllow[alias] = utils.MergeAddressLists(hostAllow[alias], hostAllow[domain])
+			hostDeny[alias] = utils.MergeAddressLists(hostDeny[alias], hostDeny[domain])
+			hostBasicAuthUsers[alias] = hostBasicAuthUsers[domain]
+			hostRequestHeaders[alias] = hostRequestHeaders[domain]
+			hostProtocol[alias] = hostProtocol[domain]
+			hostResponseHeaders[alias] = hostResponseHeaders[domain]
+			hostErrorPages[alias] = hostErrorPages[domain]
+			hostStickyCookie[alias] = hostStickyCookie[domain]
+			hostHealthChecks[alias] = hostHealthChecks[domain]
+			if c, ok := hostChecks[domain]; ok {
+				hostChecks[alias] = c
+			}
+			if maintenanceDomains[domain] {
+				maintenanceDomains[alias] = true
+			}
 		}
 
 		proxyUpstreams[domain] = append(proxyUpstreams[domain], up)
 	}
 
+	// domains in maintenance without upstreams are still served
+	for d := range maintenanceDomains {
+		if _, ok := proxyUpstreams[d]; ok {
+			continue
+		}
+
+		if _, ok := hostContextRoots[d]; !ok {
+			hostContextRoots[d] = &ContextRoot{}
+		}
+
+		if _, ok := hostBalanceAlgorithms[d]; !ok {
+			hostBalanceAlgorithms[d] = utils.DefaultBalanceAlgorithm
+		}
+
+		if _, ok := hostErrorPages[d]; !ok {
+			pages, err := p.errorPages(nil, d, proxyFiles)
+			if err != nil {
+				p.reloadLog().WithField("domain", d).Errorf("error loading error pages: %s", err)
+			} else {
+				hostErrorPages[d] = pages
+			}
+		}
+
+		proxyUpstreams[d] = []*Upstream{}
+	}
+
+	// domains without auth are not served; the other upstreams of the
+	// domain would be exposed without auth otherwise
+	for d := range authFailed {
+		p.reloadLog().WithField("domain", d).Warn("basic auth failed to load; removing domain")
+		delete(proxyUpstreams, d)
+	}
+
 	for k, v := range proxyUpstreams {
 		name := strings.Replace(k, ".", "_", -1)
 		host := &Host{
@@ -180,20 +423,121 @@ func (p *HAProxyLoadBalancer) GenerateProxyConfig(containers []types.Container)
 			Domain:              k,
 			Upstreams:           v,
 			Check:               hostChecks[k],
+			HealthCheck:         hostHealthChecks[k],
 			BalanceAlgorithm:    hostBalanceAlgorithms[k],
+			StickyCookie:        hostStickyCookie[k],
 			BackendOptions:      hostBackendOptions[k],
 			SSLOnly:             hostSSLOnly[k],
 			SSLBackend:          hostSSLBackend[k],
 			SSLBackendTLSVerify: hostSSLBackendTLSVerify[k],
+			SSLCert:             hostSSLCert[k],
+			Maintenance:         maintenanceDomains[k],
+			ErrorPages:          hostErrorPages[k],
+		}
+
+		if r, ok := hostRateLimit[k]; ok {
+			host.RateLimit = r.Rate
+			host.RateLimitPeriod = fmt.Sprintf("1%s", r.Period)
+		} else {
+			host.RateLimitPeriod = "1s"
+		}
+
+		host.ConnLimit = hostConnLimit[k]
+		host.Allow = hostAllow[k]
+		host.Deny = hostDeny[k]
+		host.BasicAuthUsers = hostBasicAuthUsers[k]
+		// http/2 upstreams use alpn with ssl and prior knowledge without
+		if utils.HTTP2(hostProtocol[k]) {
+			host.HTTP2 = true
+			if host.SSLBackend {
+				host.ServerALPN = "h2"
+			} else {
+				host.ServerProto = "h2"
+			}
 		}
-		log().Debugf("adding host name=%s domain=%s contextroot=%v", host.Name, host.Domain, host.ContextRoot)
+
+		host.RequestHeaders = hostRequestHeaders[k]
+		host.ResponseHeaders = hostResponseHeaders[k]
+
+		if h := hostLimitKeyHeader[k]; h != "" {
+			host.LimitKey = fmt.Sprintf("req.hdr(%s)", h)
+			host.LimitKeyType = "string len 64"
+		} else {
+			host.LimitKey = "src"
+			host.LimitKeyType = "ip"
+		}
+
+		p.reloadLog().WithField("domain", host.Domain).Debugf("adding host name=%s contextroot=%v", host.Name, host.ContextRoot)
 		hosts = append(hosts, host)
 	}
 
+	// static routes from the config and marker containers
+	configRoutes, err := utils.ConfigRoutes(p.cfg.Routes)
+	if err != nil {
+		p.reloadLog().Errorf("error parsing routes: %s", err)
+	} else {
+		staticRoutes = append(configRoutes, staticRoutes...)
+	}
+
+	upstreamDomains := map[string]bool{}
+	for k := range proxyUpstreams {
+		upstreamDomains[k] = true
+	}
+
+	files := []*utils.ProxyFile{}
+	for name, data := range certBundles {
+		files = append(files, &utils.ProxyFile{
+			Name: path.Join(certBundleDir, name),
+			Mode: 0600,
+			Data: data,
+		})
+	}
+
+	certBundlePath := ""
+	if len(files) > 0 {
+		certBundlePath = path.Join(p.cfg.ConfigBasePath, certBundleDir)
+	}
+
+	for _, f := range proxyFiles {
+		files = append(files, f)
+	}
+
+	http2 := false
+	for _, h := range hosts {
+		if h.HTTP2 {
+			http2 = true
+			break
+		}
+	}
+
+	// empty server slots for runtime api updates
+	if p.slotsEnabled() {
+		p.addSlots(hosts, networks)
+	}
+
+	// domains and containers for the proxy stats
+	backendDomains := map[string]string{}
+	serverContainers := map[string]string{}
+	for _, h := range hosts {
+		b := backendName(h)
+		backendDomains[b] = h.Domain
+		for _, up := range h.Upstreams {
+			if !up.Disabled {
+				serverContainers[b+"/"+up.Container] = up.Container
+			}
+		}
+	}
+	p.labels.Reset(backendDomains, serverContainers)
+
 	cfg := &Config{
-		Hosts:    hosts,
-		Config:   p.cfg,
-		Networks: networks,
+		Hosts:          hosts,
+		Config:         p.cfg,
+		Networks:       networks,
+		CertBundlePath: certBundlePath,
+		Files:          files,
+		HTTP2:          http2,
+		Routes:         utils.FilterRoutes(staticRoutes, upstreamDomains),
+		RuntimeSocket:  runtimeSocket,
 	}
 
 	return cfg, nil
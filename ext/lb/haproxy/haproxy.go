@@ -9,6 +9,10 @@ import (
 	"github.com/docker/engine-api/types"
 	etypes "github.com/docker/engine-api/types/events"
 	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext/lb/health"
+	"github.com/ehazlett/interlock/ext/lb/secrets"
+	"github.com/ehazlett/interlock/ext/lb/stats"
+	"github.com/ehazlett/interlock/ext/lb/utils"
 	"golang.org/x/net/context"
 )
 
@@ -17,8 +21,15 @@ const (
 )
 
 type HAProxyLoadBalancer struct {
-	cfg    *config.ExtensionConfig
-	client *client.Client
+	cfg         *config.ExtensionConfig
+	client      *client.Client
+	secrets     *secrets.SecretStore
+	maintenance *utils.Maintenance
+	drains      *utils.Drains
+	prober      *health.Prober
+	slots       *serverSlots
+	labels      *stats.Labels
+	reloadID    string
 }
 
 func log() *logrus.Entry {
@@ -27,10 +38,30 @@ func log() *logrus.Entry {
 	})
 }
 
-func NewHAProxyLoadBalancer(c *config.ExtensionConfig, cl *client.Client) (*HAProxyLoadBalancer, error) {
+// SetReloadID sets the id of the reload in progress for the logs of the
+// config generation and proxy reload
+func (p *HAProxyLoadBalancer) SetReloadID(id string) {
+	p.reloadID = id
+}
+
+func (p *HAProxyLoadBalancer) reloadLog() *logrus.Entry {
+	if p.reloadID == "" {
+		return log()
+	}
+
+	return log().WithField("reload_id", p.reloadID)
+}
+
+func NewHAProxyLoadBalancer(c *config.ExtensionConfig, cl *client.Client, s *secrets.SecretStore, m *utils.Maintenance, d *utils.Drains, pr *health.Prober) (*HAProxyLoadBalancer, error) {
 	lb := &HAProxyLoadBalancer{
-		cfg:    c,
-		client: cl,
+		cfg:         c,
+		client:      cl,
+		secrets:     s,
+		maintenance: m,
+		drains:      d,
+		prober:      pr,
+		slots:       newServerSlots(),
+		labels:      stats.NewLabels(),
 	}
 
 	return lb, nil
@@ -66,23 +97,29 @@ func (p *HAProxyLoadBalancer) Template() string {
 func (p *HAProxyLoadBalancer) Reload(proxyContainers []types.Container) error {
 	// drop SYN to allow for restarts
 	if err := p.dropSYN(); err != nil {
-		log().Warnf("error signaling clients to resend; you will notice dropped packets: %s", err)
+		p.reloadLog().Warnf("error signaling clients to resend; you will notice dropped packets: %s", err)
 	}
 
+	failed := map[string]error{}
 	for _, cnt := range proxyContainers {
 		// restart
-		log().Debugf("restarting proxy container: id=%s", cnt.ID)
+		p.reloadLog().WithField("container_id", cnt.ID).Debug("restarting proxy container")
 		d := time.Millisecond * 1000
 		if err := p.client.ContainerRestart(context.Background(), cnt.ID, &d); err != nil {
-			log().Errorf("error restarting container: id=%s err=%s", cnt.ID[:12], err)
+			p.reloadLog().WithField("container_id", cnt.ID).Errorf("error restarting container: err=%s", err)
+			failed[cnt.ID] = err
 			continue
 		}
 
-		log().Infof("restarted proxy container: id=%s name=%s", cnt.ID[:12], cnt.Names[0])
+		p.reloadLog().WithField("container_id", cnt.ID).Infof("restarted proxy container: name=%s", cnt.Names[0])
 	}
 
 	if err := p.resumeSYN(); err != nil {
-		log().Warnf("error signaling clients to resume; you will notice dropped packets: %s", err)
+		p.reloadLog().Warnf("error signaling clients to resume; you will notice dropped packets: %s", err)
+	}
+
+	if len(failed) > 0 {
+		return &utils.ReloadError{Failed: failed}
 	}
 
 	return nil
@@ -0,0 +1,54 @@
+package haproxy
+
+import (
+	"errors"
+
+	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/ext/lb/utils"
+	"golang.org/x/net/context"
+)
+
+var errNoPortsExposed = errors.New("no ports exposed")
+
+// backendAddr returns the upstream address for the container and the
+// overlay network used to reach it if any
+func (p *HAProxyLoadBalancer) backendAddr(cInfo types.ContainerJSON) (string, string, error) {
+	if n, ok := utils.OverlayEnabled(cInfo.Config); ok {
+		log().Debugf("configuring docker network: name=%s", n)
+
+		// FIXME: for some reason the request from dockerclient
+		// is not returning a populated Networks object
+		// so we hack this by inspecting the Network
+		// we should switch to engine-api/client -- hopefully
+		// that will fix
+		network, err := p.client.NetworkInspect(context.Background(), n)
+		if err != nil {
+			return "", "", err
+		}
+
+		addr, err := utils.BackendOverlayAddress(network, cInfo)
+		if err != nil {
+			return "", "", err
+		}
+
+		return addr, n, nil
+	}
+
+	portsExposed := false
+	for _, portBindings := range cInfo.NetworkSettings.Ports {
+		if len(portBindings) != 0 {
+			portsExposed = true
+			break
+		}
+	}
+	if !portsExposed {
+		return "", "", errNoPortsExposed
+	}
+
+	addr, err := utils.BackendAddress(cInfo, p.cfg.BackendOverrideAddress)
+	if err != nil {
+		return "", "", err
+	}
+
+	return addr, "", nil
+}
@@ -0,0 +1,183 @@
+package haproxy
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+const (
+	runtimeTimeout = time.Second * 5
+	// the admin socket is only reachable through the runtime-api proxy
+	// which only accepts connections from interlock
+	runtimeSocket = "/var/run/haproxy-runtime.sock"
+)
+
+// runtimeCommand sends a command to the haproxy runtime api (stats socket)
+// at addr and returns the response
+func runtimeCommand(addr, cmd string) (string, error) {
+	conn, err := net.DialTimeout("tcp", addr, runtimeTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(runtimeTimeout)); err != nil {
+		return "", err
+	}
+
+	if _, err := fmt.Fprintf(conn, "%s\n", cmd); err != nil {
+		return "", err
+	}
+
+	// haproxy closes the connection after the response in
+	// non-interactive mode
+	data, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// runtimeSet sends a command that returns an empty response on success
+func runtimeSet(addr, cmd string) error {
+	resp, err := runtimeCommand(addr, cmd)
+	if err != nil {
+		return err
+	}
+
+	if r := strings.TrimSpace(resp); r != "" {
+		return fmt.Errorf("%s: %s", cmd, r)
+	}
+
+	return nil
+}
+
+// runtimeSetAddr changes the address of the server (backend/server)
+func runtimeSetAddr(addr, server, host, port string) error {
+	cmd := fmt.Sprintf("set server %s addr %s port %s", server, host, port)
+	resp, err := runtimeCommand(addr, cmd)
+	if err != nil {
+		return err
+	}
+
+	// haproxy reports the change on success
+	if r := strings.TrimSpace(resp); !strings.Contains(r, "changed") && !strings.Contains(r, "no need to change") {
+		return fmt.Errorf("%s: %s", cmd, r)
+	}
+
+	return nil
+}
+
+// runtimeStats returns the rows of "show stat" keyed by the csv header
+func runtimeStats(addr string) ([]map[string]string, error) {
+	resp, err := runtimeCommand(addr, "show stat")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStats(resp)
+}
+
+func parseStats(data string) ([]map[string]string, error) {
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(data, "# ")))
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty stats response")
+	}
+
+	header := records[0]
+	rows := []map[string]string{}
+	for _, rec := range records[1:] {
+		row := map[string]string{}
+		for i, v := range rec {
+			if i < len(header) {
+				row[header[i]] = v
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// serverBackends returns the haproxy backends and the server name for the
+// container
+func serverBackends(cInfo types.ContainerJSON) ([]string, string) {
+	server := strings.TrimPrefix(cInfo.Name, "/")
+
+	if contextRoot := utils.ContextRoot(cInfo.Config); contextRoot != "" {
+		return []string{"ctx" + utils.ServiceDomain(cInfo.Config)}, server
+	}
+
+	backends := []string{}
+	domains := append([]string{utils.ServiceDomain(cInfo.Config)}, utils.AliasDomains(cInfo.Config)...)
+	for _, d := range domains {
+		if d == "" {
+			continue
+		}
+		backends = append(backends, strings.Replace(d, ".", "_", -1))
+	}
+
+	return backends, server
+}
+
+// Drain puts the container servers into drain mode on the proxy with the
+// runtime api at addr
+func (p *HAProxyLoadBalancer) Drain(addr string, cInfo types.ContainerJSON) error {
+	backends, _ := serverBackends(cInfo)
+	for _, b := range backends {
+		server := p.serverName(b, cInfo)
+		log().Infof("draining server: proxy=%s backend=%s server=%s", addr, b, server)
+		if err := runtimeSet(addr, fmt.Sprintf("set server %s/%s state drain", b, server)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Connections returns the current connections to the container servers
+// on the proxy with the runtime api at addr
+func (p *HAProxyLoadBalancer) Connections(addr string, cInfo types.ContainerJSON) (int, error) {
+	rows, err := runtimeStats(addr)
+	if err != nil {
+		return 0, err
+	}
+
+	backends, _ := serverBackends(cInfo)
+	servers := map[string]string{}
+	for _, b := range backends {
+		servers[b] = p.serverName(b, cInfo)
+	}
+
+	conns := 0
+	for _, row := range rows {
+		server, ok := servers[row["pxname"]]
+		if !ok || row["svname"] != server {
+			continue
+		}
+
+		n, err := strconv.Atoi(row["scur"])
+		if err != nil {
+			return 0, fmt.Errorf("invalid connection count for %s/%s: %s", row["pxname"], server, row["scur"])
+		}
+		conns += n
+	}
+
+	return conns, nil
+}
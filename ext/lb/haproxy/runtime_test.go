@@ -0,0 +1,127 @@
+package haproxy
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext"
+)
+
+const testStats = `# pxname,svname,qcur,qmax,scur,smax,status
+app_example_com,FRONTEND,,,0,1,OPEN
+app_example_com,app1,0,0,2,3,UP
+app_example_com,app2,0,0,1,1,UP
+www_example_com,app1,0,0,1,1,UP
+`
+
+// runtimeStub serves the haproxy runtime api and records the commands
+func runtimeStub(t *testing.T, responses map[string]string) (string, chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			cmd, _ := bufio.NewReader(conn).ReadString('\n')
+			cmd = strings.TrimSpace(cmd)
+			cmds <- cmd
+
+			switch {
+			case strings.HasPrefix(cmd, "show stat"):
+				conn.Write([]byte(testStats))
+			case strings.Contains(cmd, " addr "):
+				conn.Write([]byte("IP changed from '127.0.0.1' to '10.0.0.1' by 'stats socket command'\n"))
+			default:
+				conn.Write([]byte(responses[cmd]))
+			}
+			conn.Close()
+		}
+	}()
+
+	return l.Addr().String(), cmds
+}
+
+func testContainer() types.ContainerJSON {
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			Name: "/app1",
+		},
+		Config: &ctypes.Config{
+			Hostname: "app",
+			Labels: map[string]string{
+				ext.InterlockDomainLabel:      "example.com",
+				ext.InterlockAliasDomainLabel: "www.example.com",
+			},
+		},
+	}
+}
+
+func TestParseStats(t *testing.T) {
+	rows, err := parseStats(testStats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows; received %d", len(rows))
+	}
+
+	if rows[1]["svname"] != "app1" || rows[1]["scur"] != "2" {
+		t.Fatalf("unexpected row: %v", rows[1])
+	}
+}
+
+func TestDrain(t *testing.T) {
+	addr, cmds := runtimeStub(t, nil)
+	p := &HAProxyLoadBalancer{cfg: &config.ExtensionConfig{}, slots: newServerSlots()}
+
+	if err := p.Drain(addr, testContainer()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"set server app_example_com/app1 state drain",
+		"set server www_example_com/app1 state drain",
+	} {
+		if cmd := <-cmds; cmd != expected {
+			t.Fatalf("expected %q; received %q", expected, cmd)
+		}
+	}
+}
+
+func TestDrainError(t *testing.T) {
+	addr, _ := runtimeStub(t, map[string]string{
+		"set server app_example_com/app1 state drain": "No such server.\n",
+	})
+	p := &HAProxyLoadBalancer{cfg: &config.ExtensionConfig{}, slots: newServerSlots()}
+
+	if err := p.Drain(addr, testContainer()); err == nil {
+		t.Fatal("expected error for unknown server")
+	}
+}
+
+func TestConnections(t *testing.T) {
+	addr, _ := runtimeStub(t, nil)
+	p := &HAProxyLoadBalancer{cfg: &config.ExtensionConfig{}, slots: newServerSlots()}
+
+	conns, err := p.Connections(addr, testContainer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conns != 3 {
+		t.Fatalf("expected 3 connections; received %d", conns)
+	}
+}
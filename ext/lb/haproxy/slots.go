@@ -0,0 +1,304 @@
+package haproxy
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/ext/lb/health"
+	"github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+const (
+	slotPrefix = "interlock_slot"
+	// placeholder address for empty slots; haproxy requires a valid
+	// address for disabled servers
+	slotAddr = "127.0.0.1:1"
+)
+
+// slot is a server in a haproxy backend.  Empty slots are rendered
+// disabled and are filled through the runtime api.
+type slot struct {
+	ID      string // container id; empty if the slot is free
+	Dynamic bool   // added through the runtime api
+}
+
+// serverSlots tracks the servers in the haproxy backends so upstreams can
+// be added and removed through the runtime api without a reload
+type serverSlots struct {
+	lock     *sync.Mutex
+	backends map[string]map[string]*slot // backend -> server -> slot
+	networks map[string]bool
+	removed  map[string]bool
+}
+
+func newServerSlots() *serverSlots {
+	return &serverSlots{
+		lock:     &sync.Mutex{},
+		backends: map[string]map[string]*slot{},
+		networks: map[string]bool{},
+		removed:  map[string]bool{},
+	}
+}
+
+// slotsEnabled returns true if the backends have server slots
+func (p *HAProxyLoadBalancer) slotsEnabled() bool {
+	return p.cfg.RuntimeAPIPort > 0 && p.cfg.ServerSlots > 0
+}
+
+// backendName returns the haproxy backend for the host
+func backendName(h *Host) string {
+	if h.ContextRoot != nil && h.ContextRoot.Path != "" {
+		return "ctx" + h.ContextRoot.Name
+	}
+
+	return h.Name
+}
+
+// addSlots adds the empty server slots to the hosts and resets the slot
+// state to the generated config
+func (p *HAProxyLoadBalancer) addSlots(hosts []*Host, networks map[string]string) {
+	s := newServerSlots()
+
+	for n := range networks {
+		s.networks[n] = true
+	}
+
+	for _, h := range hosts {
+		// hosts in maintenance have no servers
+		if h.Maintenance {
+			continue
+		}
+
+		servers := map[string]*slot{}
+		interval := utils.DefaultHealthCheckInterval
+		for _, up := range h.Upstreams {
+			servers[up.Container] = &slot{ID: up.ID}
+			interval = up.CheckInterval
+		}
+
+		for i := 0; i < p.cfg.ServerSlots; i++ {
+			name := fmt.Sprintf("%s%d", slotPrefix, i)
+			servers[name] = &slot{Dynamic: true}
+
+			h.Upstreams = append(h.Upstreams, &Upstream{
+				Container:     name,
+				Addr:          slotAddr,
+				CheckInterval: interval,
+				Cookie:        utils.StickyCookieValue(name),
+				Disabled:      true,
+			})
+		}
+
+		s.backends[backendName(h)] = servers
+	}
+
+	p.slots.lock.Lock()
+	defer p.slots.lock.Unlock()
+
+	p.slots.backends = s.backends
+	p.slots.networks = s.networks
+	p.slots.removed = s.removed
+}
+
+// serverName returns the server for the container in the backend
+func (p *HAProxyLoadBalancer) serverName(backend string, cInfo types.ContainerJSON) string {
+	p.slots.lock.Lock()
+	defer p.slots.lock.Unlock()
+
+	for name, sl := range p.slots.backends[backend] {
+		if sl.Dynamic && sl.ID != "" && sl.ID == cInfo.ID {
+			return name
+		}
+	}
+
+	return strings.TrimPrefix(cInfo.Name, "/")
+}
+
+// freeSlot returns the first free slot in the backend
+func freeSlot(servers map[string]*slot) string {
+	names := []string{}
+	for name, sl := range servers {
+		if sl.Dynamic && sl.ID == "" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	sort.Strings(names)
+
+	return names[0]
+}
+
+// AddServer adds the container to free server slots on the proxies with
+// the runtime api at proxyAddrs.  It returns false if a reload is
+// required (i.e. a new host or no free slots).
+func (p *HAProxyLoadBalancer) AddServer(proxyAddrs []string, cInfo types.ContainerJSON) (bool, error) {
+	if !p.slotsEnabled() {
+		return false, nil
+	}
+
+	backends, _ := serverBackends(cInfo)
+	if len(backends) == 0 {
+		return false, nil
+	}
+
+	addr, network, err := p.backendAddr(cInfo)
+	if err != nil {
+		return false, err
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false, err
+	}
+
+	if p.cfg.RequireHealthy && !health.DockerHealthy(cInfo.State) {
+		// added on the health_status event reload
+		log().WithField("container_id", cInfo.ID).Info("container not healthy; not adding server")
+		return true, nil
+	}
+
+	healthCheck, err := utils.HealthCheckConfig(cInfo.Config)
+	if err != nil {
+		return false, err
+	}
+
+	if !p.prober.Healthy(&health.Target{
+		ID:     cInfo.ID,
+		Addr:   addr,
+		SSL:    utils.SSLBackend(cInfo.Config),
+		Check:  healthCheck,
+		Status: health.DockerStatus(cInfo.State),
+	}) {
+		// added on the reload once the upstream recovers
+		return true, nil
+	}
+
+	p.slots.lock.Lock()
+	defer p.slots.lock.Unlock()
+
+	if network != "" && !p.slots.networks[network] {
+		log().WithField("network", network).Debug("proxy not connected to network; reload required")
+		return false, nil
+	}
+
+	// reserve a slot in each backend
+	servers := map[string]string{}
+	for _, b := range backends {
+		bSlots, ok := p.slots.backends[b]
+		if !ok {
+			log().Debugf("new backend; reload required: backend=%s", b)
+			return false, nil
+		}
+
+		// already in the generated config
+		if !p.slots.removed[cInfo.ID] {
+			for _, sl := range bSlots {
+				if sl.ID != "" && sl.ID == cInfo.ID {
+					return true, nil
+				}
+			}
+		}
+
+		name := freeSlot(bSlots)
+		if name == "" {
+			log().Debugf("no free server slots; reload required: backend=%s", b)
+			return false, nil
+		}
+
+		servers[b] = name
+	}
+
+	weight := 1
+	if utils.Drain(cInfo.Config) {
+		weight = 0
+	}
+
+	for _, proxyAddr := range proxyAddrs {
+		for b, name := range servers {
+			server := b + "/" + name
+			if err := runtimeSetAddr(proxyAddr, server, host, port); err != nil {
+				return false, err
+			}
+
+			if err := runtimeSet(proxyAddr, fmt.Sprintf("set weight %s %d", server, weight)); err != nil {
+				return false, err
+			}
+
+			if err := runtimeSet(proxyAddr, "enable server "+server); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	for b, name := range servers {
+		p.slots.backends[b][name].ID = cInfo.ID
+		p.labels.SetContainer(b+"/"+name, strings.TrimPrefix(cInfo.Name, "/"))
+		log().WithFields(logrus.Fields{
+			"container_id": cInfo.ID,
+			"container":    strings.TrimPrefix(cInfo.Name, "/"),
+			"backend":      b,
+			"server":       name,
+			"upstream":     addr,
+		}).Info("added server")
+	}
+	delete(p.slots.removed, cInfo.ID)
+
+	return true, nil
+}
+
+// RemoveServer disables the servers for the container on the proxies
+// with the runtime api at proxyAddrs and frees the slots.  It returns
+// false if the container is unknown and a reload is required.
+func (p *HAProxyLoadBalancer) RemoveServer(proxyAddrs []string, id string) (bool, error) {
+	if !p.slotsEnabled() {
+		return false, nil
+	}
+
+	p.slots.lock.Lock()
+	defer p.slots.lock.Unlock()
+
+	if p.slots.removed[id] {
+		return true, nil
+	}
+
+	servers := map[string]*slot{}
+	for b, bSlots := range p.slots.backends {
+		for name, sl := range bSlots {
+			if sl.ID != "" && sl.ID == id {
+				servers[b+"/"+name] = sl
+			}
+		}
+	}
+
+	if len(servers) == 0 {
+		return false, nil
+	}
+
+	for _, proxyAddr := range proxyAddrs {
+		for server := range servers {
+			if err := runtimeSet(proxyAddr, "disable server "+server); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	for server, sl := range servers {
+		// rendered servers stay disabled until the next reload
+		if sl.Dynamic {
+			sl.ID = ""
+		}
+		log().WithFields(logrus.Fields{"container_id": id, "server": server}).Info("removed server")
+	}
+	p.slots.removed[id] = true
+
+	return true, nil
+}
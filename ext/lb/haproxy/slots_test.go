@@ -0,0 +1,159 @@
+package haproxy
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	"github.com/docker/go-connections/nat"
+	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext/lb/stats"
+)
+
+func testSlotsLoadBalancer() *HAProxyLoadBalancer {
+	return &HAProxyLoadBalancer{
+		cfg: &config.ExtensionConfig{
+			RuntimeAPIPort: 9999,
+			ServerSlots:    2,
+		},
+		slots:  newServerSlots(),
+		labels: stats.NewLabels(),
+	}
+}
+
+func testSlotsHosts() []*Host {
+	return []*Host{
+		{
+			Name:        "app_example_com",
+			ContextRoot: &ContextRoot{},
+			Upstreams: []*Upstream{
+				{ID: "abc", Container: "app0", Addr: "10.0.0.1:32000", CheckInterval: 5000},
+			},
+		},
+		{
+			Name:        "www_example_com",
+			ContextRoot: &ContextRoot{},
+			Upstreams: []*Upstream{
+				{ID: "abc", Container: "app0", Addr: "10.0.0.1:32000", CheckInterval: 5000},
+			},
+		},
+	}
+}
+
+func TestAddSlots(t *testing.T) {
+	p := testSlotsLoadBalancer()
+	hosts := testSlotsHosts()
+	p.addSlots(hosts, nil)
+
+	ups := hosts[0].Upstreams
+	if len(ups) != 3 {
+		t.Fatalf("expected 3 upstreams; received %d", len(ups))
+	}
+
+	if ups[1].Container != "interlock_slot0" || !ups[1].Disabled || ups[1].Addr != slotAddr {
+		t.Fatalf("unexpected slot: %+v", ups[1])
+	}
+
+	if ups[1].CheckInterval != 5000 {
+		t.Fatalf("expected check interval 5000; received %d", ups[1].CheckInterval)
+	}
+}
+
+func TestAddRemoveServer(t *testing.T) {
+	addr, cmds := runtimeStub(t, nil)
+
+	p := testSlotsLoadBalancer()
+	p.addSlots(testSlotsHosts(), nil)
+
+	cInfo := testContainer()
+	cInfo.ID = "def"
+	cInfo.NetworkSettings = &types.NetworkSettings{}
+	cInfo.NetworkSettings.Ports = nat.PortMap{
+		"80/tcp": []nat.PortBinding{
+			{
+				HostIP:   "10.0.0.1",
+				HostPort: "32768",
+			},
+		},
+	}
+
+	ok, err := p.AddServer([]string{addr}, cInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Fatal("expected server to be added without a reload")
+	}
+
+	expected := map[string]bool{
+		"set server app_example_com/interlock_slot0 addr 10.0.0.1 port 32768": true,
+		"set weight app_example_com/interlock_slot0 1":                        true,
+		"enable server app_example_com/interlock_slot0":                       true,
+		"set server www_example_com/interlock_slot0 addr 10.0.0.1 port 32768": true,
+		"set weight www_example_com/interlock_slot0 1":                        true,
+		"enable server www_example_com/interlock_slot0":                       true,
+	}
+
+	for i := 0; i < len(expected); i++ {
+		if cmd := <-cmds; !expected[cmd] {
+			t.Fatalf("unexpected command: %q", cmd)
+		}
+	}
+
+	if s := p.serverName("app_example_com", cInfo); s != "interlock_slot0" {
+		t.Fatalf("expected interlock_slot0; received %s", s)
+	}
+
+	ok, err = p.RemoveServer([]string{addr}, cInfo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Fatal("expected server to be removed without a reload")
+	}
+
+	for i := 0; i < 2; i++ {
+		if cmd := <-cmds; cmd != "disable server app_example_com/interlock_slot0" && cmd != "disable server www_example_com/interlock_slot0" {
+			t.Fatalf("unexpected command: %q", cmd)
+		}
+	}
+
+	if s := freeSlot(p.slots.backends["app_example_com"]); s != "interlock_slot0" {
+		t.Fatalf("expected interlock_slot0 to be free; received %q", s)
+	}
+}
+
+func TestAddServerNewBackend(t *testing.T) {
+	p := testSlotsLoadBalancer()
+	p.addSlots(testSlotsHosts()[:1], nil)
+
+	cInfo := testContainer()
+	cInfo.NetworkSettings = &types.NetworkSettings{}
+	cInfo.NetworkSettings.Ports = nat.PortMap{
+		"80/tcp": []nat.PortBinding{{HostIP: "10.0.0.1", HostPort: "32768"}},
+	}
+
+	ok, err := p.AddServer([]string{"127.0.0.1:0"}, cInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok {
+		t.Fatal("expected a reload for a new backend")
+	}
+}
+
+func TestRemoveServerUnknown(t *testing.T) {
+	p := testSlotsLoadBalancer()
+	p.addSlots(testSlotsHosts(), nil)
+
+	ok, err := p.RemoveServer([]string{"127.0.0.1:0"}, "unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok {
+		t.Fatal("expected a reload for an unknown container")
+	}
+}
@@ -0,0 +1,100 @@
+package haproxy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/ehazlett/interlock/ext/lb/stats"
+)
+
+const (
+	statsPath = "/haproxy?stats;csv"
+)
+
+// Stats scrapes the traffic stats from the proxy stats page at addr
+func (p *HAProxyLoadBalancer) Stats(addr string) ([]*stats.Sample, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s%s", addr, statsPath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.cfg.AdminUser != "" {
+		req.SetBasicAuth(p.cfg.AdminUser, p.cfg.AdminPass)
+	}
+
+	client := &http.Client{
+		Timeout: runtimeTimeout,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from haproxy stats: %d", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := parseStats(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return p.statsSamples(rows), nil
+}
+
+// statsSamples converts the haproxy csv stats to samples labelled with the
+// interlock domains and containers
+func (p *HAProxyLoadBalancer) statsSamples(rows []map[string]string) []*stats.Sample {
+	samples := []*stats.Sample{}
+	for _, row := range rows {
+		name := row["pxname"]
+		s := &stats.Sample{
+			Name:         name,
+			Domain:       p.labels.Domain(name),
+			Requests:     statValue(row["req_tot"]),
+			Responses5xx: statValue(row["hrsp_5xx"]),
+			Queue:        statValue(row["qcur"]),
+			Active:       statValue(row["scur"]),
+		}
+
+		// older versions only report requests for frontends
+		if row["req_tot"] == "" {
+			s.Requests = statValue(row["stot"])
+		}
+
+		switch row["svname"] {
+		case "FRONTEND":
+			s.Type = stats.TypeFrontend
+		case "BACKEND":
+			s.Type = stats.TypeBackend
+		default:
+			s.Type = stats.TypeServer
+			s.Container = p.labels.Container(name + "/" + row["svname"])
+			if s.Container == "" {
+				s.Container = row["svname"]
+			}
+		}
+
+		samples = append(samples, s)
+	}
+
+	return samples
+}
+
+func statValue(v string) float64 {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0
+	}
+
+	return f
+}
@@ -0,0 +1,70 @@
+package haproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext/lb/stats"
+)
+
+const testStatsCSV = `# pxname,svname,qcur,qmax,scur,smax,slim,stot,req_tot,hrsp_5xx
+http-default,FRONTEND,,,3,10,,100,120,2
+app_example_com,interlock_slot0,1,2,2,3,,50,,1
+app_example_com,BACKEND,1,2,2,3,,50,,1
+`
+
+func TestStats(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.RawQuery != "stats;csv" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(testStatsCSV))
+	}))
+	defer s.Close()
+
+	p := &HAProxyLoadBalancer{
+		cfg: &config.ExtensionConfig{
+			AdminUser: "admin",
+			AdminPass: "secret",
+		},
+		labels: stats.NewLabels(),
+	}
+	p.labels.Reset(
+		map[string]string{"app_example_com": "app.example.com"},
+		map[string]string{"app_example_com/interlock_slot0": "app1"},
+	)
+
+	samples, err := p.Stats(strings.TrimPrefix(s.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 samples; received %d", len(samples))
+	}
+
+	fe := samples[0]
+	if fe.Type != stats.TypeFrontend || fe.Requests != 120 || fe.Responses5xx != 2 || fe.Active != 3 {
+		t.Fatalf("unexpected frontend sample: %+v", fe)
+	}
+
+	srv := samples[1]
+	if srv.Type != stats.TypeServer || srv.Domain != "app.example.com" || srv.Container != "app1" {
+		t.Fatalf("unexpected server sample: %+v", srv)
+	}
+
+	// servers fall back to sessions for requests
+	if srv.Requests != 50 || srv.Queue != 1 {
+		t.Fatalf("unexpected server counts: %+v", srv)
+	}
+}
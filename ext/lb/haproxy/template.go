@@ -11,12 +11,14 @@ global
     pidfile {{ .Config.PidPath }}
     ssl-server-verify {{ .Config.SSLServerVerify }}
     tune.ssl.default-dh-param {{ .Config.SSLDefaultDHParam }}
+    {{ if .Config.RuntimeAPIPort }}stats socket {{ .RuntimeSocket }} mode 600 level admin{{ end }}
 
 defaults
     mode http
     retries 3
     option redispatch
     option httplog
+    {{ if .Config.AccessLogEndpoint }}log {{ .Config.AccessLogEndpoint }} local2 info{{ end }}
     option dontlognull
     option http-server-close
     option forwardfor
@@ -24,38 +26,69 @@ defaults
     timeout client {{ .Config.ClientTimeout }}
     timeout server {{ .Config.ServerTimeout }}
 
+{{ range $host := .Hosts }}{{ if $host.BasicAuthUsers }}userlist {{ $host.Name }}_users
+    {{ range $user := $host.BasicAuthUsers }}user {{ $user.Name }} password {{ $user.Password }}
+    {{ end }}
+{{ end }}{{ end }}
 frontend http-default
     bind *:{{ .Config.Port }}
-    {{ if .Config.SSLCert }}bind *:{{ .Config.SSLPort }} ssl crt {{ .Config.SSLCert }} {{ .Config.SSLOpts }}{{ end }}
+    {{ if or .Config.SSLCert .CertBundlePath }}bind *:{{ .Config.SSLPort }} ssl{{ if .Config.SSLCert }} crt {{ .Config.SSLCert }}{{ end }}{{ if .CertBundlePath }} crt {{ .CertBundlePath }}{{ end }}{{ if .HTTP2 }} alpn h2,http/1.1{{ end }} {{ .Config.SSLOpts }}{{ end }}
     monitor-uri /haproxy?monitor
     {{ if .Config.AdminUser }}stats realm Stats
     stats auth {{ .Config.AdminUser }}:{{ .Config.AdminPass}}{{ end }}
     stats enable
     stats uri /haproxy?stats
     stats refresh 5s
-    {{ range $host := .Hosts }}{{ if ne $host.ContextRoot.Path "" }}acl url{{ $host.ContextRoot.Name }} path_beg {{ $host.ContextRoot.Path }}
+    {{ range $route := .Routes }}acl route_{{ $route.Name }} hdr(host),field(1,:) -i {{ $route.Domain }}
+    {{ if $route.Redirect }}http-request redirect {{ if $route.PreservePath }}prefix{{ else }}location{{ end }} {{ $route.Redirect }} code {{ $route.Code }} if route_{{ $route.Name }}{{ else }}http-request return status {{ $route.Code }} content-type text/plain string {{ $route.QuotedBody }} if route_{{ $route.Name }}{{ end }}
+    {{ end }}{{ range $host := .Hosts }}{{ if ne $host.ContextRoot.Path "" }}acl url{{ $host.ContextRoot.Name }} path_beg {{ $host.ContextRoot.Path }}
     use_backend ctx{{ $host.ContextRoot.Name }} if url{{ $host.ContextRoot.Name }}{{ else }}
     acl is_{{ $host.Name }} hdr_beg(host) {{ $host.Domain }}
     use_backend {{ $host.Name }} if is_{{ $host.Name }}
     {{ end }}
     {{ end }}
 
+{{ if .Config.RuntimeAPIPort }}listen runtime-api
+    mode tcp
+    no log
+    bind *:{{ .Config.RuntimeAPIPort }}
+    acl interlock src{{ range $addr := .Config.RuntimeAPIClients }} {{ $addr }}{{ end }}
+    tcp-request connection reject if !interlock
+    server runtime {{ .RuntimeSocket }}
+{{ end }}
 {{ range $host := .Hosts }}{{ if ne $host.ContextRoot.Path "" }}backend ctx{{ $host.ContextRoot.Name }}
     acl missing_slash path_reg ^{{ $host.ContextRoot.Path }}[^/]*$
     redirect code 301 prefix / drop-query append-slash if missing_slash
-    {{ if $host.ContextRootRewrite }}reqrep ^([^\ :]*)\ {{ $host.ContextRoot.Path }}/(.*)     \1\ /\2{{ end }}{{ else }}
+    {{ if $host.ContextRootRewrite }}http-request replace-path ^{{ $host.ContextRoot.Path }}/(.*) /\1{{ end }}{{ else }}
     backend {{ $host.Name }}{{ end }}
     http-response add-header X-Request-Start %Ts.%ms
     http-request set-header X-Forwarded-Port %[dst_port]
     http-request add-header X-Forwarded-Proto https if { ssl_fc }
-    balance {{ $host.BalanceAlgorithm }}
+    {{ range $h := $host.RequestHeaders }}http-request set-header {{ $h.Name }} {{ $h.Quoted }}
+    {{ end }}{{ range $h := $host.ResponseHeaders }}http-response set-header {{ $h.Name }} {{ $h.Quoted }}
+    {{ end }}    balance {{ $host.BalanceAlgorithm }}
+    {{ if $host.StickyCookie }}cookie {{ $host.StickyCookie }} insert indirect nocache{{ end }}
+    {{ if $host.Deny }}acl {{ $host.Name }}_deny src{{ range $addr := $host.Deny }} {{ $addr }}{{ end }}
+    http-request deny if {{ $host.Name }}_deny{{ end }}
+    {{ if $host.Allow }}acl {{ $host.Name }}_allow src{{ range $addr := $host.Allow }} {{ $addr }}{{ end }}
+    http-request deny if !{{ $host.Name }}_allow{{ end }}
+    {{ if $host.BasicAuthUsers }}acl {{ $host.Name }}_auth http_auth({{ $host.Name }}_users)
+    http-request auth realm Restricted if !{{ $host.Name }}_auth{{ end }}
+    {{ if or $host.RateLimit $host.ConnLimit }}stick-table type {{ $host.LimitKeyType }} size 100k expire 2m store http_req_rate({{ $host.RateLimitPeriod }}),conn_cur
+    http-request track-sc0 {{ $host.LimitKey }}{{ end }}
+    {{ if $host.RateLimit }}http-request deny deny_status 429 if { sc_http_req_rate(0) gt {{ $host.RateLimit }} }{{ end }}
+    {{ if $host.ConnLimit }}http-request deny deny_status 429 if { sc_conn_cur(0) gt {{ $host.ConnLimit }} }{{ end }}
+    {{ if $host.HTTP2 }}option http-keep-alive{{ end }}
     {{ range $option := $host.BackendOptions }}option {{ $option }}
     {{ end }}
     {{ if $host.Check }}option {{ $host.Check }}{{ end }}
+    {{ with $host.HealthCheck }}{{ if .Status }}http-check expect status {{ .Status }}{{ end }}
+    {{ if .Timeout }}timeout check {{ .Timeout }}ms{{ end }}{{ end }}
     {{ if $host.SSLOnly }}redirect scheme https code 301 if !{ ssl_fc }{{ end }}
 	{{ if $host.SSLOnly }}http-response set-header Strict-Transport-Security "max-age=16000000; includeSubDomains; preload;"{{ end }}
-    {{ range $i,$up := $host.Upstreams }}server {{ $up.Container }} {{ $up.Addr }} check inter {{ $up.CheckInterval }}{{ if $host.SSLBackend }} ssl verify {{ $host.SSLBackendTLSVerify }} sni req.hdr(Host){{ end }}
-    {{ end }}
+    {{ range $page := $host.ErrorPages }}errorfile {{ $page.Code }} {{ $page.Path }}
+    {{ end }}{{ if not $host.Maintenance }}{{ range $i,$up := $host.Upstreams }}server {{ $up.Container }} {{ $up.Addr }} check inter {{ $up.CheckInterval }}{{ with $host.HealthCheck }}{{ if .Rise }} rise {{ .Rise }}{{ end }}{{ if .Fall }} fall {{ .Fall }}{{ end }}{{ end }}{{ if $host.StickyCookie }} cookie {{ $up.Cookie }}{{ end }}{{ if $up.Drain }} weight 0{{ end }}{{ if $up.Disabled }} disabled{{ end }}{{ if $host.SSLBackend }} ssl verify {{ $host.SSLBackendTLSVerify }} sni req.hdr(Host){{ end }}{{ if $host.ServerALPN }} alpn {{ $host.ServerALPN }}{{ end }}{{ if $host.ServerProto }} proto {{ $host.ServerProto }}{{ end }}
+    {{ end }}{{ end }}
 {{ end }}
 `
 )
@@ -0,0 +1,16 @@
+package lb
+
+import (
+	"github.com/ehazlett/interlock/ext/lb/health"
+	"golang.org/x/net/context"
+)
+
+// healthStatus returns the docker health status for the container
+func (l *LoadBalancer) healthStatus(id string) (string, error) {
+	c, err := l.client.ContainerInspect(context.Background(), id)
+	if err != nil {
+		return "", err
+	}
+
+	return health.DockerStatus(c.State), nil
+}
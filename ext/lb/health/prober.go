@@ -0,0 +1,318 @@
+package health
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+const (
+	pluginName = "health"
+)
+
+func log() *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{
+		"ext": pluginName,
+	})
+}
+
+// StatusFunc returns the current docker health status for a container
+type StatusFunc func(id string) (string, error)
+
+// Target is a candidate upstream to probe
+type Target struct {
+	ID     string
+	Addr   string
+	SSL    bool
+	Check  *utils.HealthCheckSpec
+	Status string // docker health status; empty if the container has no healthcheck
+}
+
+// Prober probes candidate upstreams before they are added to the proxy
+// config.  Config generation only uses the cached results; new upstreams
+// are probed in the background and left out of the config until they
+// pass.  The change func is called when an upstream passes or fails so
+// the config can be updated.
+type Prober struct {
+	timeout   time.Duration
+	status    StatusFunc
+	onChange  func()
+	transport *http.Transport
+	lock      *sync.Mutex
+	results   map[string]*result // container id -> result
+	previous  map[string]*result // results of the previous config generation
+}
+
+// result is the last probe result for a target
+type result struct {
+	target  *Target
+	healthy bool
+	pending bool // the target has not been probed yet
+}
+
+func NewProber(timeout time.Duration, status StatusFunc, onChange func()) *Prober {
+	return &Prober{
+		timeout:  timeout,
+		status:   status,
+		onChange: onChange,
+		transport: &http.Transport{
+			// upstream certificates are verified by the proxy
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		lock:     &sync.Mutex{},
+		results:  map[string]*result{},
+		previous: map[string]*result{},
+	}
+}
+
+// DockerStatus returns the docker health status from the container state
+func DockerStatus(state *types.ContainerState) string {
+	if state == nil || state.Health == nil {
+		return ""
+	}
+
+	return state.Health.Status
+}
+
+// DockerHealthy returns true if the container reports healthy or has no
+// healthcheck
+func DockerHealthy(state *types.ContainerState) bool {
+	status := DockerStatus(state)
+	return status == "" || status == types.Healthy
+}
+
+// Reset starts a config generation.  Upstreams that are not requested
+// again before the next reset are no longer probed.
+func (p *Prober) Reset() {
+	if p == nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.previous = p.results
+	p.results = map[string]*result{}
+}
+
+// Healthy returns true if the target passes.  The cached result is used
+// for known targets so config generation does not wait on upstreams; new
+// targets are probed in the background and fail until the probe passes.
+// Targets that only use the docker health status do not need a network
+// probe and are checked right away.  A nil prober always passes.
+func (p *Prober) Healthy(t *Target) bool {
+	if p == nil {
+		return true
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	r, ok := p.results[t.ID]
+	if !ok {
+		r, ok = p.previous[t.ID]
+	}
+	if ok && r.target.equal(t) {
+		p.results[t.ID] = r
+		return r.healthy
+	}
+
+	if !t.Check.HTTP() && t.Status != "" {
+		err := p.probe(t)
+		if err != nil {
+			log().WithField("container_id", t.ID).Warnf("upstream failed probe; excluding: addr=%s err=%s", t.Addr, err)
+		}
+
+		p.results[t.ID] = &result{
+			target:  t,
+			healthy: err == nil,
+		}
+
+		return err == nil
+	}
+
+	r = &result{
+		target:  t,
+		pending: true,
+	}
+	p.results[t.ID] = r
+
+	log().WithField("container_id", t.ID).Debugf("probing new upstream: addr=%s", t.Addr)
+	go func() {
+		if p.recheck(r) && p.onChange != nil {
+			p.onChange()
+		}
+	}()
+
+	return false
+}
+
+// Run probes the upstreams at the interval and calls the change func if
+// any pass or fail
+func (p *Prober) Run(interval time.Duration) {
+	t := time.NewTicker(interval)
+	go func() {
+		for range t.C {
+			p.check()
+		}
+	}()
+}
+
+func (p *Prober) check() {
+	p.lock.Lock()
+	results := []*result{}
+	for _, r := range p.results {
+		results = append(results, r)
+	}
+	p.lock.Unlock()
+
+	wg := &sync.WaitGroup{}
+	changed := make(chan bool, len(results))
+	for _, r := range results {
+		wg.Add(1)
+		go func(r *result) {
+			defer wg.Done()
+
+			if p.recheck(r) {
+				changed <- true
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	if len(changed) > 0 && p.onChange != nil {
+		p.onChange()
+	}
+}
+
+// recheck probes the target again and returns true if the result changed
+func (p *Prober) recheck(r *result) bool {
+	t := r.target
+
+	// refresh the docker health status
+	if t.Status != "" && p.status != nil {
+		s, err := p.status(t.ID)
+		if err != nil {
+			log().WithField("container_id", t.ID).Debugf("unable to get health status: err=%s", err)
+			return false
+		}
+
+		c := *t
+		c.Status = s
+		t = &c
+	}
+
+	err := p.probe(t)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// only update targets that have not been reset
+	if p.results[t.ID] != r {
+		return false
+	}
+
+	healthy := err == nil
+	p.results[t.ID] = &result{
+		target:  t,
+		healthy: healthy,
+	}
+
+	if healthy == r.healthy {
+		switch {
+		case err != nil && r.pending:
+			log().WithField("container_id", t.ID).Warnf("upstream failed probe; excluding: addr=%s err=%s", t.Addr, err)
+		case err != nil:
+			log().WithField("container_id", t.ID).Debugf("upstream still failing: addr=%s err=%s", t.Addr, err)
+		}
+		return false
+	}
+
+	if healthy {
+		log().WithField("container_id", t.ID).Infof("upstream passed probe: addr=%s", t.Addr)
+	} else {
+		log().WithField("container_id", t.ID).Warnf("upstream failed probe: addr=%s err=%s", t.Addr, err)
+	}
+
+	return true
+}
+
+// probe checks the target with an http request if a path is set, the
+// docker health status if the container has a healthcheck and a tcp
+// connection otherwise
+func (p *Prober) probe(t *Target) error {
+	switch {
+	case t.Check.HTTP():
+		return p.probeHTTP(t)
+	case t.Status != "":
+		if t.Status != types.Healthy {
+			return fmt.Errorf("container is %s", t.Status)
+		}
+		return nil
+	default:
+		conn, err := net.DialTimeout("tcp", t.Addr, p.probeTimeout(t))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+func (p *Prober) probeHTTP(t *Target) error {
+	scheme := "http"
+	if t.SSL {
+		scheme = "https"
+	}
+
+	client := &http.Client{
+		Timeout:   p.probeTimeout(t),
+		Transport: p.transport,
+	}
+
+	resp, err := client.Get(fmt.Sprintf("%s://%s%s", scheme, t.Addr, t.Check.Path))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if t.Check.Status != 0 {
+		if resp.StatusCode != t.Check.Status {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (p *Prober) probeTimeout(t *Target) time.Duration {
+	if t.Check != nil && t.Check.Timeout > 0 {
+		return time.Duration(t.Check.Timeout) * time.Millisecond
+	}
+
+	return p.timeout
+}
+
+// equal returns true if the targets are probed the same way
+func (t *Target) equal(o *Target) bool {
+	if t.Addr != o.Addr || t.SSL != o.SSL || t.Status != o.Status {
+		return false
+	}
+
+	if t.Check == nil || o.Check == nil {
+		return t.Check == o.Check
+	}
+
+	return *t.Check == *o.Check
+}
@@ -0,0 +1,275 @@
+package health
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+func testServerAddr(s *httptest.Server) string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// waitProbed waits for the first probe of the target to finish
+func waitProbed(t *testing.T, p *Prober, id string) {
+	for i := 0; i < 100; i++ {
+		p.lock.Lock()
+		r, ok := p.results[id]
+		p.lock.Unlock()
+
+		if ok && !r.pending {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("expected %s to be probed", id)
+}
+
+func TestProberNil(t *testing.T) {
+	var p *Prober
+	if !p.Healthy(&Target{ID: "test", Addr: "127.0.0.1:1"}) {
+		t.Fatal("expected nil prober to pass")
+	}
+}
+
+func TestProberTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+
+	p := NewProber(time.Second, nil, nil)
+
+	if p.Healthy(&Target{ID: "test", Addr: addr}) {
+		t.Fatal("expected new upstream to be excluded until probed")
+	}
+
+	waitProbed(t, p, "test")
+
+	if !p.Healthy(&Target{ID: "test", Addr: addr}) {
+		t.Fatal("expected tcp probe to pass")
+	}
+
+	l.Close()
+
+	// the cached result is used until the upstream is probed again
+	if !p.Healthy(&Target{ID: "test", Addr: addr}) {
+		t.Fatal("expected cached tcp probe to pass")
+	}
+
+	p.check()
+
+	if p.Healthy(&Target{ID: "test", Addr: addr}) {
+		t.Fatal("expected tcp probe to fail")
+	}
+}
+
+func TestProberHTTP(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer s.Close()
+
+	recovered := false
+	p := NewProber(time.Second, nil, func() {
+		recovered = true
+	})
+
+	target := &Target{
+		ID:   "test",
+		Addr: testServerAddr(s),
+		Check: &utils.HealthCheckSpec{
+			Path:   "/health",
+			Status: http.StatusOK,
+		},
+	}
+
+	if p.Healthy(target) {
+		t.Fatal("expected new upstream to be excluded until probed")
+	}
+
+	waitProbed(t, p, "test")
+
+	if p.Healthy(target) {
+		t.Fatal("expected http probe to fail")
+	}
+
+	p.check()
+	if recovered {
+		t.Fatal("expected upstream to still be failing")
+	}
+
+	status = http.StatusOK
+	p.check()
+
+	if !recovered {
+		t.Fatal("expected upstream to recover")
+	}
+
+	if !p.Healthy(target) {
+		t.Fatal("expected http probe to pass")
+	}
+}
+
+func TestProberNewTargetAsync(t *testing.T) {
+	release := make(chan bool)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer s.Close()
+
+	changed := make(chan bool, 1)
+	p := NewProber(5*time.Second, nil, func() {
+		changed <- true
+	})
+
+	target := &Target{
+		ID:    "test",
+		Addr:  testServerAddr(s),
+		Check: &utils.HealthCheckSpec{Path: "/health"},
+	}
+
+	// a slow upstream must not hold up config generation
+	start := time.Now()
+	if p.Healthy(target) {
+		t.Fatal("expected new upstream to be excluded until probed")
+	}
+
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("expected new upstream to be probed in the background; waited %s", d)
+	}
+
+	close(release)
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected change when the new upstream passes")
+	}
+
+	if !p.Healthy(target) {
+		t.Fatal("expected http probe to pass")
+	}
+}
+
+func TestProberDockerStatus(t *testing.T) {
+	status := "starting"
+	recovered := false
+	p := NewProber(time.Second, func(id string) (string, error) {
+		return status, nil
+	}, func() {
+		recovered = true
+	})
+
+	state := &types.ContainerState{
+		Health: &types.Health{
+			Status: status,
+		},
+	}
+
+	if p.Healthy(&Target{ID: "test", Addr: "127.0.0.1:1", Status: DockerStatus(state)}) {
+		t.Fatal("expected starting container to fail")
+	}
+
+	status = types.Healthy
+	p.check()
+
+	if !recovered {
+		t.Fatal("expected healthy container to recover")
+	}
+}
+
+func TestProberReset(t *testing.T) {
+	recovered := false
+	p := NewProber(time.Second, func(id string) (string, error) {
+		return types.Healthy, nil
+	}, func() {
+		recovered = true
+	})
+
+	p.Healthy(&Target{ID: "test", Status: "unhealthy"})
+	p.Reset()
+	p.check()
+
+	if recovered {
+		t.Fatal("expected reset upstream to be ignored")
+	}
+}
+
+func TestProberCached(t *testing.T) {
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer s.Close()
+
+	p := NewProber(time.Second, nil, nil)
+
+	target := &Target{
+		ID:    "test",
+		Addr:  testServerAddr(s),
+		Check: &utils.HealthCheckSpec{Path: "/health"},
+	}
+
+	p.Healthy(target)
+	waitProbed(t, p, "test")
+
+	for i := 0; i < 3; i++ {
+		p.Reset()
+		if !p.Healthy(target) {
+			t.Fatal("expected http probe to pass")
+		}
+	}
+
+	if requests != 1 {
+		t.Fatalf("expected 1 probe request; received %d", requests)
+	}
+
+	// targets probed differently are probed again
+	p.Healthy(&Target{
+		ID:    "test",
+		Addr:  testServerAddr(s),
+		Check: &utils.HealthCheckSpec{Path: "/ready"},
+	})
+	waitProbed(t, p, "test")
+
+	if requests != 2 {
+		t.Fatalf("expected 2 probe requests; received %d", requests)
+	}
+}
+
+func TestDockerHealthy(t *testing.T) {
+	if !DockerHealthy(&types.ContainerState{}) {
+		t.Fatal("expected container without healthcheck to be healthy")
+	}
+
+	for status, expected := range map[string]bool{
+		types.Healthy:   true,
+		types.Unhealthy: false,
+		types.Starting:  false,
+	} {
+		state := &types.ContainerState{
+			Health: &types.Health{
+				Status: status,
+			},
+		}
+
+		if DockerHealthy(state) != expected {
+			t.Fatalf("expected healthy=%v for %s", expected, status)
+		}
+	}
+}
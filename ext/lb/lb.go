@@ -16,19 +16,24 @@ import (
 	"github.com/docker/engine-api/client"
 	"github.com/docker/engine-api/types"
 	etypes "github.com/docker/engine-api/types/events"
-	ntypes "github.com/docker/engine-api/types/network"
 	"github.com/ehazlett/interlock/config"
 	"github.com/ehazlett/interlock/ext"
+	"github.com/ehazlett/interlock/ext/lb/certs"
 	"github.com/ehazlett/interlock/ext/lb/haproxy"
+	"github.com/ehazlett/interlock/ext/lb/health"
 	"github.com/ehazlett/interlock/ext/lb/nginx"
+	"github.com/ehazlett/interlock/ext/lb/secrets"
+	lbutils "github.com/ehazlett/interlock/ext/lb/utils"
+	"github.com/ehazlett/interlock/tracing"
 	"github.com/ehazlett/interlock/utils"
 	"github.com/ehazlett/ttlcache"
 	"golang.org/x/net/context"
 )
 
 const (
-	pluginName      = "lb"
-	ReloadThreshold = time.Millisecond * 2000
+	pluginName        = "lb"
+	ReloadThreshold   = time.Millisecond * 2000
+	certCheckInterval = time.Hour * 1
 )
 
 var (
@@ -49,15 +54,29 @@ type LoadBalancerBackend interface {
 	GenerateProxyConfig(c []types.Container) (interface{}, error)
 	Template() string
 	Reload(proxyContainers []types.Container) error
+	// SetReloadID sets the id of the reload in progress for the logs
+	SetReloadID(id string)
 }
 
 type LoadBalancer struct {
-	nodeID  string
-	cfg     *config.ExtensionConfig
-	client  *client.Client
-	cache   *ttlcache.TTLCache
-	lock    *sync.Mutex
-	backend LoadBalancerBackend
+	nodeID      string
+	cfg         *config.ExtensionConfig
+	client      *client.Client
+	cache       *ttlcache.TTLCache
+	lock        *sync.Mutex
+	backend     LoadBalancerBackend
+	certs       []*certs.Cert
+	maintenance *lbutils.Maintenance
+	drains      *lbutils.Drains
+	networks    map[string]string // interlock container addresses by network
+	// reload records
+	triggers      []string
+	state         map[string]*hostState
+	reloads       []*ext.Reload
+	reloadHandler func(r *ext.Reload)
+	reloadID      string
+	triggerCtx    context.Context
+	triggered     time.Time
 }
 
 func log() *logrus.Entry {
@@ -114,26 +133,75 @@ func NewLoadBalancer(c *config.ExtensionConfig, client *client.Client) (*LoadBal
 		return nil, err
 	}
 
-	log().Infof("interlock node: container id=%s", containerID)
+	log().WithField("container_id", containerID).Info("interlock node")
 
 	extension := &LoadBalancer{
-		cfg:    c,
-		client: client,
-		cache:  cache,
-		lock:   &sync.Mutex{},
-		nodeID: containerID,
+		cfg:         c,
+		client:      client,
+		cache:       cache,
+		lock:        &sync.Mutex{},
+		nodeID:      containerID,
+		maintenance: lbutils.NewMaintenance(),
+		drains:      lbutils.NewDrains(),
+	}
+
+	// secret store for tls material
+	var secretStore *secrets.SecretStore
+	if c.SecretStoreAddr != "" {
+		s, err := secrets.NewSecretStore(c.SecretStoreAddr, c.SecretStoreKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("error setting up secret store: %s", err)
+		}
+
+		secretStore = s
+
+		d, err := time.ParseDuration(c.SecretStorePollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse secret store poll interval: %s", err)
+		}
+
+		// watch for rotated secrets and trigger a reload to update the proxies
+		t := time.NewTicker(d)
+		go func() {
+			for range t.C {
+				if rotated := secretStore.Rotated(); len(rotated) > 0 {
+					log().Infof("secrets rotated; triggering reload: keys=%s", strings.Join(rotated, ","))
+					extension.triggerReload(context.Background(), "secrets-rotated")
+				}
+			}
+		}()
+	}
+
+	// probe upstreams before adding them to the proxy config
+	var prober *health.Prober
+	if c.UpstreamProbeInterval != "" {
+		interval, err := time.ParseDuration(c.UpstreamProbeInterval)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse upstream probe interval: %s", err)
+		}
+
+		timeout, err := time.ParseDuration(c.UpstreamProbeTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse upstream probe timeout: %s", err)
+		}
+
+		prober = health.NewProber(timeout, extension.healthStatus, func() {
+			log().Info("upstream probes changed; triggering reload")
+			extension.triggerReload(context.Background(), "upstream-probe")
+		})
+		prober.Run(interval)
 	}
 
 	// select backend
 	switch c.Name {
 	case "haproxy":
-		p, err := haproxy.NewHAProxyLoadBalancer(c, client)
+		p, err := haproxy.NewHAProxyLoadBalancer(c, client, secretStore, extension.maintenance, extension.drains, prober)
 		if err != nil {
 			return nil, fmt.Errorf("error setting backend: %s", err)
 		}
 		extension.backend = p
 	case "nginx":
-		p, err := nginx.NewNginxLoadBalancer(c, client)
+		p, err := nginx.NewNginxLoadBalancer(c, client, secretStore, extension.maintenance, extension.drains, prober)
 		if err != nil {
 			return nil, fmt.Errorf("error setting backend: %s", err)
 		}
@@ -142,6 +210,43 @@ func NewLoadBalancer(c *config.ExtensionConfig, client *client.Client) (*LoadBal
 		return nil, fmt.Errorf("unknown load balancer backend: %s", c.Name)
 	}
 
+	// only allow interlock to use the proxy runtime api
+	if c.RuntimeAPIPort > 0 {
+		if err := extension.setRuntimeAPIClients(); err != nil {
+			return nil, err
+		}
+	}
+
+	// receive the access logs from the proxy containers
+	if c.AccessLogAddr != "" {
+		if err := extension.startAccessLog(); err != nil {
+			return nil, err
+		}
+	}
+
+	// scrape traffic stats from the proxy containers
+	if c.ProxyStatsInterval != "" {
+		d, err := time.ParseDuration(c.ProxyStatsInterval)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse proxy stats interval: %s", err)
+		}
+
+		t := time.NewTicker(d)
+		go func() {
+			for range t.C {
+				extension.scrapeStats()
+			}
+		}()
+	}
+
+	// periodically check the ssl certs as expiry changes over time
+	certTicker := time.NewTicker(certCheckInterval)
+	go func() {
+		for range certTicker.C {
+			extension.checkCerts()
+		}
+	}()
+
 	// proxy network cleanup chan
 	// this waits for a reload event and removes the proxy containers
 	// from unused proxy networks
@@ -155,7 +260,7 @@ func NewLoadBalancer(c *config.ExtensionConfig, client *client.Client) (*LoadBal
 				cID := c.ContainerID
 				cnt, err := client.ContainerInspect(context.Background(), cID)
 				if err != nil {
-					log().Errorf("error inspecting proxy container: id=%s err=%s", cID, err)
+					log().WithField("container_id", cID).Errorf("error inspecting proxy container: err=%s", err)
 					continue
 				}
 
@@ -167,7 +272,7 @@ func NewLoadBalancer(c *config.ExtensionConfig, client *client.Client) (*LoadBal
 
 					if _, ok := c.ProxyNetworks[net]; !ok {
 						// attempt to disconnect
-						log().Debugf("disconnecting proxy container from network: id=%s net=%s", cID, net)
+						log().WithFields(logrus.Fields{"container_id": cID, "network": net}).Debug("disconnecting proxy container from network")
 
 						retries := 5
 						for i := 0; i < retries; i++ {
@@ -176,7 +281,7 @@ func NewLoadBalancer(c *config.ExtensionConfig, client *client.Client) (*LoadBal
 								break
 							}
 
-							log().Warnf("unable to disconnect proxy container %s from network %s (retrying): %s", cID, net, err)
+							log().WithFields(logrus.Fields{"container_id": cID, "network": net}).Warnf("unable to disconnect proxy container from network (retrying): err=%s", err)
 
 							// wait for network to disconnect
 							time.Sleep(2 * time.Second)
@@ -194,127 +299,11 @@ func NewLoadBalancer(c *config.ExtensionConfig, client *client.Client) (*LoadBal
 			log().Debug("checking to reload")
 			if v := extension.cache.Get("reload"); v != nil {
 				log().Debug("skipping reload: too many requests")
+				reloadsSkipped.WithLabelValues(extension.backend.Name()).Inc()
 				continue
 			}
 
-			start := time.Now()
-
-			log().Debug("updating load balancers")
-
-			opts := types.ContainerListOptions{
-				All: true,
-			}
-			containers, err := client.ContainerList(context.Background(), opts)
-			if err != nil {
-				errChan <- err
-				continue
-			}
-
-			// generate proxy config
-			log().Debug("generating proxy config")
-			cfg, err := extension.backend.GenerateProxyConfig(containers)
-			if err != nil {
-				errChan <- err
-				continue
-			}
-
-			// save proxy config
-			configPath := extension.backend.ConfigPath()
-			log().Debugf("proxy config path: %s", configPath)
-
-			proxyNetworks := map[string]string{}
-
-			proxyContainers, err := extension.ProxyContainers(extension.backend.Name())
-			if err != nil {
-				errChan <- err
-				continue
-			}
-
-			log().Debugf("proxyContainers: %v", proxyContainers)
-
-			// save config
-			log().Debug("saving proxy config")
-			if err := extension.SaveConfig(configPath, cfg, proxyContainers); err != nil {
-				errChan <- err
-				continue
-			}
-
-			// connect to networks
-			switch extension.backend.Name() {
-			case "nginx":
-				proxyConfig := cfg.(*nginx.Config)
-				proxyNetworks = proxyConfig.Networks
-			case "haproxy":
-				proxyConfig := cfg.(*haproxy.Config)
-				proxyNetworks = proxyConfig.Networks
-			default:
-				errChan <- fmt.Errorf("unable to connect to networks; unknown backend: %s", extension.backend.Name())
-				continue
-			}
-
-			proxyContainerNetworkConfigs := []proxyContainerNetworkConfig{}
-
-			for _, cnt := range proxyContainers {
-				proxyContainerNetworkConfigs = append(proxyContainerNetworkConfigs, proxyContainerNetworkConfig{
-					ContainerID:   cnt.ID,
-					ProxyNetworks: proxyNetworks,
-				})
-				for net, _ := range proxyNetworks {
-					if _, ok := cnt.NetworkSettings.Networks[net]; !ok {
-						log().Debugf("connecting proxy container %s to network %s", cnt.ID, net)
-
-						// connect
-						if err := client.NetworkConnect(context.Background(), net, cnt.ID, &ntypes.EndpointSettings{}); err != nil {
-							log().Warnf("unable to connect container %s to network %s: %s", cnt.ID, net, err)
-							continue
-						}
-					}
-				}
-			}
-
-			// get interlock nodes
-			interlockNodes := []types.Container{}
-
-			for _, cnt := range containers {
-				// always include self container
-				if cnt.ID == containerID && cnt.State == "running" {
-					interlockNodes = append(interlockNodes, cnt)
-					continue
-				}
-
-				cInfo, err := client.ContainerInspect(context.Background(), cnt.ID)
-				if err != nil {
-					log().Errorf("unable to inspect interlock container: %s", err)
-					continue
-				}
-
-				if strings.Index(cInfo.Config.Image, "interlock") > 0 {
-					if _, ok := cInfo.Config.Labels[ext.InterlockAppLabel]; ok {
-						interlockNodes = append(interlockNodes, cnt)
-					}
-				}
-			}
-
-			proxyContainersToRestart := extension.proxyContainersToRestart(interlockNodes, proxyContainers)
-
-			// trigger reload
-			log().Debug("signaling reload")
-
-			// pause to ensure file write sync
-			time.Sleep(time.Millisecond * 1000)
-			if err := extension.backend.Reload(proxyContainersToRestart); err != nil {
-				log().Error(err)
-				errChan <- err
-				continue
-			}
-
-			d := time.Since(start)
-			duration := float64(d.Seconds() * float64(1000))
-
-			//log().Debug("triggering proxy network cleanup")
-			//proxyNetworkCleanupChan <- proxyContainerNetworkConfigs
-
-			log().Infof("reload duration: %0.2fms", duration)
+			extension.reload()
 		}
 	}()
 
@@ -339,7 +328,7 @@ func (l *LoadBalancer) ProxyContainers(name string) ([]types.Container, error) {
 	// find interlock proxy containers
 	for _, cnt := range containers {
 		if v, ok := cnt.Labels[ext.InterlockExtNameLabel]; ok && v == l.backend.Name() {
-			log().Debugf("detected proxy container: id=%s backend=%v", cnt.ID, v)
+			log().WithField("container_id", cnt.ID).Debugf("detected proxy container: backend=%v", v)
 			proxyContainers = append(proxyContainers, cnt)
 		}
 	}
@@ -347,7 +336,31 @@ func (l *LoadBalancer) ProxyContainers(name string) ([]types.Container, error) {
 	return proxyContainers, nil
 }
 
+// renderedConfig is the proxy config and additional files copied to the
+// proxy containers
+type renderedConfig struct {
+	Data  []byte
+	Files []*lbutils.ProxyFile
+}
+
 func (l *LoadBalancer) SaveConfig(configPath string, cfg interface{}, proxyContainers []types.Container) error {
+	c, err := l.renderConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	// proxy containers that could not be updated are logged
+	if err := l.copyConfig(context.Background(), c, proxyContainers); err != nil {
+		if _, ok := err.(*lbutils.ReloadError); !ok {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderConfig executes the backend template with the proxy config
+func (l *LoadBalancer) renderConfig(cfg interface{}) (*renderedConfig, error) {
 	t := template.New("lb")
 	confTmpl := l.backend.Template()
 
@@ -355,39 +368,58 @@ func (l *LoadBalancer) SaveConfig(configPath string, cfg interface{}, proxyConta
 
 	tmpl, err := t.Parse(confTmpl)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	// additional files (certs, etc) to copy with the config
+	files := []*lbutils.ProxyFile{}
+
 	// cast to config type
 	switch l.backend.Name() {
 	case "nginx":
 		config := cfg.(*nginx.Config)
 		if err := tmpl.Execute(&c, config); err != nil {
-			return err
+			return nil, err
 		}
+		files = config.Files
 	case "haproxy":
 		config := cfg.(*haproxy.Config)
 		if err := tmpl.Execute(&c, config); err != nil {
-			return err
+			return nil, err
 		}
+		files = config.Files
 	default:
-		return fmt.Errorf("unknown backend type: %s", l.backend.Name())
+		return nil, fmt.Errorf("unknown backend type: %s", l.backend.Name())
 	}
 
+	return &renderedConfig{
+		Data:  c.Bytes(),
+		Files: files,
+	}, nil
+}
+
+// copyConfig copies the rendered config to the proxy containers.  A
+// *lbutils.ReloadError is returned with the containers that could not be
+// updated.
+func (l *LoadBalancer) copyConfig(ctx context.Context, c *renderedConfig, proxyContainers []types.Container) error {
 	fName := path.Base(l.backend.ConfigPath())
 	proxyConfigPath := path.Dir(l.backend.ConfigPath())
 
-	data := c.Bytes()
+	data := c.Data
+	files := c.Files
 
 	// copy to proxy nodes
+	failed := map[string]error{}
 	for _, cnt := range proxyContainers {
-		log().Debugf("updating proxy config: id=%s", cnt.ID)
+		l.reloadLog().WithField("container_id", cnt.ID).Debug("updating proxy config")
 		// create tar stream to copy
 		buf := new(bytes.Buffer)
 		tw := tar.NewWriter(buf)
+		// the config contains credentials (i.e. userlist password hashes)
+		// and is only read by the proxy master process
 		hdr := &tar.Header{
 			Name: fName,
-			Mode: 0644,
+			Mode: 0600,
 			Size: int64(len(data)),
 		}
 
@@ -399,6 +431,51 @@ func (l *LoadBalancer) SaveConfig(configPath string, cfg interface{}, proxyConta
 			return fmt.Errorf("error writing proxy config: %s", err)
 		}
 
+		// restrict access to directories containing additional files
+		dirs := map[string]bool{}
+		for _, f := range files {
+			dir := path.Dir(f.Name)
+			if dir == "." || dirs[dir] {
+				continue
+			}
+
+			dirs[dir] = true
+
+			// allow traversal if files in the directory are world readable
+			mode := int64(0700)
+			for _, x := range files {
+				if path.Dir(x.Name) == dir && x.Mode&0004 != 0 {
+					mode = 0755
+				}
+			}
+
+			hdr := &tar.Header{
+				Name:     dir + "/",
+				Mode:     mode,
+				Typeflag: tar.TypeDir,
+			}
+
+			if err := tw.WriteHeader(hdr); err != nil {
+				return fmt.Errorf("error writing proxy directory header: %s", err)
+			}
+		}
+
+		for _, f := range files {
+			hdr := &tar.Header{
+				Name: f.Name,
+				Mode: f.Mode,
+				Size: int64(len(f.Data)),
+			}
+
+			if err := tw.WriteHeader(hdr); err != nil {
+				return fmt.Errorf("error writing proxy file header: %s", err)
+			}
+
+			if _, err := tw.Write(f.Data); err != nil {
+				return fmt.Errorf("error writing proxy file: %s", err)
+			}
+		}
+
 		if err := tw.Close(); err != nil {
 			return fmt.Errorf("error closing tar writer: %s", err)
 		}
@@ -406,32 +483,73 @@ func (l *LoadBalancer) SaveConfig(configPath string, cfg interface{}, proxyConta
 		opts := types.CopyToContainerOptions{
 			AllowOverwriteDirWithFile: true,
 		}
-		if err := l.client.CopyToContainer(context.Background(), cnt.ID, proxyConfigPath, buf, opts); err != nil {
-			log().Errorf("error copying proxy config: %s", err)
+		_, span := tracing.StartSpan(ctx, "copy_container")
+		span.SetAttribute("container_id", cnt.ID)
+		err := l.client.CopyToContainer(context.Background(), cnt.ID, proxyConfigPath, buf, opts)
+		span.SetError(err)
+		span.Finish()
+		if err != nil {
+			l.reloadLog().Errorf("error copying proxy config: %s", err)
+			failed[cnt.ID] = err
 			continue
 		}
 	}
 
+	if len(failed) > 0 {
+		return &lbutils.ReloadError{Failed: failed}
+	}
+
 	return nil
 }
 
 func (l *LoadBalancer) HandleEvent(event *etypes.Message) error {
+	return l.HandleEventContext(context.Background(), event)
+}
+
+// HandleEventContext handles the event with the trace span in the context
+// as the parent of the event handling and reload spans
+func (l *LoadBalancer) HandleEventContext(ctx context.Context, event *etypes.Message) error {
 	reload := false
 
 	// container event
 	switch event.Status {
 	case "start":
-		reload = l.isExposedContainer(event.ID)
+		// a restarted container is no longer drained
+		l.drains.Remove(event.ID)
+		reload = l.isExposedContainer(ctx, event.ID) && !l.runtimeUpdate(ctx, event.ID, true)
+	case "kill":
+		// drain connections before the container is removed from the
+		// proxy config on a graceful stop (SIGTERM)
+		if event.Actor.Attributes["signal"] == "15" && l.isExposedContainer(ctx, event.ID) {
+			l.drain(event.ID)
+		}
 	case "stop":
-		reload = l.isExposedContainer(event.ID)
+		// the proxy config is reloaded once the connections are drained
+		if l.drains.Draining(event.ID) {
+			log().WithField("container_id", event.ID).Debug("container draining; skipping reload")
+			break
+		}
+
+		reload = l.isExposedContainer(ctx, event.ID) && !l.runtimeUpdate(ctx, event.ID, false)
 
 		// wait for container to stop
 		time.Sleep(time.Millisecond * 250)
-	case "interlock-start", "interlock-restart", "destroy":
+	case "destroy":
+		l.drains.Remove(event.ID)
+
+		// removed containers are already out of the proxy config
+		reload = !l.runtimeUpdate(ctx, event.ID, false)
+	case "interlock-start", "interlock-restart":
 		// force reload
 		reload = true
 	}
 
+	// container health event (i.e. "health_status: healthy")
+	if l.cfg.RequireHealthy && strings.HasPrefix(event.Status, "health_status") {
+		log().WithField("container_id", event.ID).Debugf("container health changed: status=%s", event.Status)
+		reload = l.isExposedContainer(ctx, event.ID)
+	}
+
 	// network event
 	switch event.Action {
 	case "connect", "disconnect":
@@ -449,9 +567,14 @@ func (l *LoadBalancer) HandleEvent(event *etypes.Message) error {
 			return fmt.Errorf("unable to detect container network name for network event")
 		}
 
+		_, span := tracing.StartSpan(ctx, "wait_network_connect")
+		span.SetAttribute("container_id", id)
+		span.SetAttribute("network", net)
 		for i := 0; i < 5; i++ {
 			connected, err := l.isContainerConnected(id, net)
 			if err != nil {
+				span.SetError(err)
+				span.Finish()
 				return err
 			}
 
@@ -459,16 +582,23 @@ func (l *LoadBalancer) HandleEvent(event *etypes.Message) error {
 				break
 			}
 
-			log().Debugf("waiting for network connect for container: id=%s net=%s", id, net)
+			log().WithFields(logrus.Fields{"container_id": id, "network": net}).Debug("waiting for network connect for container")
 			time.Sleep(time.Millisecond * 500)
 		}
+		span.Finish()
 
-		reload = l.isExposedContainer(id)
+		reload = l.isExposedContainer(ctx, id)
 	}
 
 	if reload {
-		log().Debug("triggering reload")
-		l.cache.Set("reload", true)
+		trigger := event.Status
+		if event.Action != "" && event.Action != event.Status {
+			trigger = event.Action
+		}
+		if id := event.ID; len(id) >= 12 {
+			trigger = fmt.Sprintf("%s:%s", trigger, id[:12])
+		}
+		l.triggerReload(ctx, trigger)
 	}
 
 	return nil
@@ -479,7 +609,7 @@ func (l *LoadBalancer) HandleEvent(event *etypes.Message) error {
 func (l *LoadBalancer) proxyContainersToRestart(nodes []types.Container, proxyContainers []types.Container) []types.Container {
 	numNodes := len(nodes)
 	if numNodes == 0 {
-		log().Warn("unable to detect interlock node; to ensure optimal reloads make sure interlock is visible in the swarm cluster")
+		l.reloadLog().Warn("unable to detect interlock node; to ensure optimal reloads make sure interlock is visible in the swarm cluster")
 		return proxyContainers
 	}
 
@@ -487,7 +617,7 @@ func (l *LoadBalancer) proxyContainersToRestart(nodes []types.Container, proxyCo
 		return proxyContainers
 	}
 
-	log().Debugf("calculating restart across interlock nodes: num=%d", numNodes)
+	l.reloadLog().Debugf("calculating restart across interlock nodes: num=%d", numNodes)
 
 	sub := len(proxyContainers) / numNodes
 
@@ -508,40 +638,61 @@ func (l *LoadBalancer) proxyContainersToRestart(nodes []types.Container, proxyCo
 		ids = append(ids, c.ID[:8])
 	}
 
-	log().Debugf("proxy containers to restart: num=%d containers=%s", len(containersToRestart), strings.Join(ids, ","))
+	l.reloadLog().Debugf("proxy containers to restart: num=%d containers=%s", len(containersToRestart), strings.Join(ids, ","))
 
 	return containersToRestart
 }
 
-func (l *LoadBalancer) isExposedContainer(id string) bool {
-	log().Debugf("inspecting container: id=%s", id)
+func (l *LoadBalancer) isExposedContainer(ctx context.Context, id string) bool {
+	_, span := tracing.StartSpan(ctx, "inspect")
+	span.SetAttribute("container_id", id)
+	defer span.Finish()
+
+	log().WithField("container_id", id).Debug("inspecting container")
 	c, err := l.client.ContainerInspect(context.Background(), id)
 	if err != nil {
 		// ignore inspect errors
-		log().Errorf("error: id=%s err=%s", id, err)
+		log().WithField("container_id", id).Errorf("error inspecting container: err=%s", err)
+		span.SetError(err)
 		return false
 	}
 
-	log().Debugf("checking container labels: id=%s", id)
+	log().WithField("container_id", id).Debug("checking container labels")
 	// ignore proxy containers
 	if _, ok := c.Config.Labels[ext.InterlockExtNameLabel]; ok {
-		log().Debugf("ignoring proxy container: id=%s", id)
+		log().WithField("container_id", id).Debug("ignoring proxy container")
 		return false
 	}
 
 	if _, ok := c.Config.Labels[ext.InterlockAppLabel]; ok {
-		log().Debugf("ignoring interlock container: id=%s", id)
+		log().WithField("container_id", id).Debug("ignoring interlock container")
 		return false
 	}
 
-	log().Debugf("checking container ports: id=%s", id)
+	// route and maintenance marker containers do not need exposed ports
+	if _, ok := c.Config.Labels[ext.InterlockRedirectLabel]; ok {
+		log().WithField("container_id", id).Debug("container is a redirect route; triggering reload")
+		return true
+	}
+
+	if _, ok := c.Config.Labels[ext.InterlockStaticResponseLabel]; ok {
+		log().WithField("container_id", id).Debug("container is a static response route; triggering reload")
+		return true
+	}
+
+	if _, ok := c.Config.Labels[ext.InterlockMaintenanceLabel]; ok {
+		log().WithField("container_id", id).Debug("container is a maintenance marker; triggering reload")
+		return true
+	}
+
+	log().WithField("container_id", id).Debug("checking container ports")
 	// ignore containers without exposed ports
 	if len(c.Config.ExposedPorts) == 0 {
-		log().Debugf("no ports exposed; ignoring: id=%s", id)
+		log().WithField("container_id", id).Debug("no ports exposed; ignoring")
 		return false
 	}
 
-	log().Debugf("container is monitored; triggering reload: id=%s", id)
+	log().WithField("container_id", id).Debug("container is monitored; triggering reload")
 	return true
 }
 
@@ -0,0 +1,22 @@
+package lb
+
+import (
+	"golang.org/x/net/context"
+)
+
+// MaintenanceDomains returns the domains put into maintenance mode
+// through the management api
+func (l *LoadBalancer) MaintenanceDomains() []string {
+	return l.maintenance.Domains()
+}
+
+// SetMaintenance enables or disables maintenance mode for the domain and
+// reloads the proxies if the state changed
+func (l *LoadBalancer) SetMaintenance(domain string, enabled bool) {
+	if !l.maintenance.Set(domain, enabled) {
+		return
+	}
+
+	log().WithField("domain", domain).Infof("maintenance mode updated; triggering reload: enabled=%v", enabled)
+	l.triggerReload(context.Background(), "maintenance")
+}
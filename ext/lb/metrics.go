@@ -0,0 +1,115 @@
+package lb
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	reloadPhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "interlock",
+			Subsystem: "lb",
+			Name:      "reload_phase_duration_seconds",
+			Help:      "Duration of the proxy reload phases (generate, save, copy, reload)",
+		},
+		[]string{
+			"ext",
+			"phase",
+		},
+	)
+	reloadsSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "interlock",
+			Subsystem: "lb",
+			Name:      "reloads_skipped_total",
+			Help:      "Reloads skipped by the reload threshold",
+		},
+		[]string{
+			"ext",
+		},
+	)
+	proxyFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "interlock",
+			Subsystem: "lb",
+			Name:      "proxy_failures_total",
+			Help:      "Failures updating a proxy container by phase (copy, reload)",
+		},
+		[]string{
+			"ext",
+			"container",
+			"phase",
+		},
+	)
+	networkConnectFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "interlock",
+			Subsystem: "lb",
+			Name:      "network_connect_failures_total",
+			Help:      "Failures connecting the proxy containers to a network",
+		},
+		[]string{
+			"ext",
+			"network",
+		},
+	)
+	accessRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "interlock",
+			Subsystem: "lb",
+			Name:      "access_requests_total",
+			Help:      "Requests in the proxy access logs by domain and status class",
+		},
+		[]string{
+			"ext",
+			"domain",
+			"code",
+		},
+	)
+	accessRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "interlock",
+			Subsystem: "lb",
+			Name:      "access_request_duration_seconds",
+			Help:      "Request duration in the proxy access logs by domain",
+		},
+		[]string{
+			"ext",
+			"domain",
+		},
+	)
+	accessResponseBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "interlock",
+			Subsystem: "lb",
+			Name:      "access_response_bytes_total",
+			Help:      "Response bytes in the proxy access logs by domain",
+		},
+		[]string{
+			"ext",
+			"domain",
+		},
+	)
+	accessLogErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "interlock",
+			Subsystem: "lb",
+			Name:      "access_log_errors_total",
+			Help:      "Proxy log messages that could not be parsed as access logs",
+		},
+		[]string{
+			"ext",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(reloadPhaseDuration)
+	prometheus.MustRegister(reloadsSkipped)
+	prometheus.MustRegister(proxyFailures)
+	prometheus.MustRegister(networkConnectFailures)
+	prometheus.MustRegister(accessRequests)
+	prometheus.MustRegister(accessRequestDuration)
+	prometheus.MustRegister(accessResponseBytes)
+	prometheus.MustRegister(accessLogErrors)
+}
@@ -0,0 +1,181 @@
+package nginx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/ext/lb/utils"
+	"golang.org/x/net/context"
+)
+
+const (
+	plusAPIVersion = 6
+	plusAPITimeout = time.Second * 5
+)
+
+var (
+	errNoPortsExposed   = errors.New("no ports exposed")
+	errPlusNotEnabled   = errors.New("nginx plus is not enabled")
+	errUpstreamNotFound = errors.New("upstream server not found")
+)
+
+// plusPeer is an upstream server from the nginx plus api
+type plusPeer struct {
+	ID     int    `json:"id"`
+	Server string `json:"server"`
+	Active int    `json:"active"`
+}
+
+type plusUpstream struct {
+	Peers []*plusPeer `json:"peers"`
+}
+
+// backendAddr returns the upstream address for the container and the
+// overlay network used to reach it if any
+func (p *NginxLoadBalancer) backendAddr(cInfo types.ContainerJSON) (string, string, error) {
+	if n, ok := utils.OverlayEnabled(cInfo.Config); ok {
+		log().Debugf("configuring docker network: name=%s", n)
+
+		network, err := p.client.NetworkInspect(context.Background(), n)
+		if err != nil {
+			return "", "", err
+		}
+
+		addr, err := utils.BackendOverlayAddress(network, cInfo)
+		if err != nil {
+			return "", "", err
+		}
+
+		return addr, n, nil
+	}
+
+	portsExposed := false
+	for _, portBindings := range cInfo.NetworkSettings.Ports {
+		if len(portBindings) != 0 {
+			portsExposed = true
+			break
+		}
+	}
+	if !portsExposed {
+		return "", "", errNoPortsExposed
+	}
+
+	addr, err := utils.BackendAddress(cInfo, p.cfg.BackendOverrideAddress)
+	if err != nil {
+		return "", "", err
+	}
+
+	return addr, "", nil
+}
+
+// upstreamName returns the nginx upstream for the container
+func upstreamName(cInfo types.ContainerJSON) string {
+	name := utils.ServiceDomain(cInfo.Config)
+	if utils.ContextRoot(cInfo.Config) != "" {
+		return "ctx" + name
+	}
+
+	return name
+}
+
+// plusAPI sends a request to the nginx plus api at addr and decodes the
+// json response into v if set
+func plusAPI(addr, method, path string, body interface{}, v interface{}) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	u := fmt.Sprintf("http://%s/api/%d%s", addr, plusAPIVersion, path)
+	req, err := http.NewRequest(method, u, &buf)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{
+		Timeout: plusAPITimeout,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("nginx plus api error: %s %s: %d %s", method, path, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	if v != nil {
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+
+	return nil
+}
+
+// upstreamPeer returns the peer for the container address from the nginx
+// plus api at addr
+func (p *NginxLoadBalancer) upstreamPeer(addr string, cInfo types.ContainerJSON) (string, *plusPeer, error) {
+	if !p.cfg.NginxPlusEnabled {
+		return "", nil, errPlusNotEnabled
+	}
+
+	serverAddr, _, err := p.backendAddr(cInfo)
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := upstreamName(cInfo)
+
+	var upstream plusUpstream
+	if err := plusAPI(addr, "GET", "/http/upstreams/"+url.PathEscape(name), nil, &upstream); err != nil {
+		return "", nil, err
+	}
+
+	for _, peer := range upstream.Peers {
+		if peer.Server == serverAddr {
+			return name, peer, nil
+		}
+	}
+
+	return "", nil, errUpstreamNotFound
+}
+
+// Drain puts the container server into drain mode on the proxy with the
+// nginx plus api at addr
+func (p *NginxLoadBalancer) Drain(addr string, cInfo types.ContainerJSON) error {
+	name, peer, err := p.upstreamPeer(addr, cInfo)
+	if err != nil {
+		return err
+	}
+
+	log().Infof("draining server: proxy=%s upstream=%s server=%s", addr, name, peer.Server)
+
+	path := fmt.Sprintf("/http/upstreams/%s/servers/%d", url.PathEscape(name), peer.ID)
+	return plusAPI(addr, "PATCH", path, map[string]bool{"drain": true}, nil)
+}
+
+// Connections returns the active connections to the container server on
+// the proxy with the nginx plus api at addr
+func (p *NginxLoadBalancer) Connections(addr string, cInfo types.ContainerJSON) (int, error) {
+	_, peer, err := p.upstreamPeer(addr, cInfo)
+	if err != nil {
+		return 0, err
+	}
+
+	return peer.Active, nil
+}
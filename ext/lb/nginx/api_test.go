@@ -0,0 +1,106 @@
+package nginx
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext"
+	"github.com/ehazlett/interlock/ext/lb/stats"
+)
+
+func testContainer() types.ContainerJSON {
+	netSettings := &types.NetworkSettings{}
+	netSettings.Ports = nat.PortMap{
+		"80/tcp": []nat.PortBinding{
+			{
+				HostIP:   "10.0.0.1",
+				HostPort: "32768",
+			},
+		},
+	}
+
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			Name: "/app1",
+		},
+		Config: &ctypes.Config{
+			Hostname: "app",
+			Labels: map[string]string{
+				ext.InterlockDomainLabel: "example.com",
+			},
+		},
+		NetworkSettings: netSettings,
+	}
+}
+
+// plusAPIStub serves the nginx plus upstream api and records the drain
+// requests
+func plusAPIStub(drained *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/api/6/http/upstreams/app.example.com":
+			json.NewEncoder(w).Encode(&plusUpstream{
+				Peers: []*plusPeer{
+					{ID: 0, Server: "10.0.0.2:32768", Active: 5},
+					{ID: 1, Server: "10.0.0.1:32768", Active: 2},
+				},
+			})
+		case r.Method == "PATCH" && strings.HasPrefix(r.URL.Path, "/api/6/http/upstreams/app.example.com/servers/"):
+			body, _ := ioutil.ReadAll(r.Body)
+			*drained = append(*drained, r.URL.Path+" "+strings.TrimSpace(string(body)))
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+}
+
+func TestDrain(t *testing.T) {
+	drained := []string{}
+	s := plusAPIStub(&drained)
+	defer s.Close()
+
+	p := &NginxLoadBalancer{cfg: &config.ExtensionConfig{NginxPlusEnabled: true}, labels: stats.NewLabels()}
+
+	if err := p.Drain(strings.TrimPrefix(s.URL, "http://"), testContainer()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `/api/6/http/upstreams/app.example.com/servers/1 {"drain":true}`
+	if len(drained) != 1 || drained[0] != expected {
+		t.Fatalf("expected %q; received %v", expected, drained)
+	}
+}
+
+func TestConnections(t *testing.T) {
+	drained := []string{}
+	s := plusAPIStub(&drained)
+	defer s.Close()
+
+	p := &NginxLoadBalancer{cfg: &config.ExtensionConfig{NginxPlusEnabled: true}, labels: stats.NewLabels()}
+
+	conns, err := p.Connections(strings.TrimPrefix(s.URL, "http://"), testContainer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conns != 2 {
+		t.Fatalf("expected 2 connections; received %d", conns)
+	}
+}
+
+func TestDrainPlusDisabled(t *testing.T) {
+	p := &NginxLoadBalancer{cfg: &config.ExtensionConfig{}}
+
+	if err := p.Drain("127.0.0.1:0", testContainer()); err != errPlusNotEnabled {
+		t.Fatalf("expected %s; received %v", errPlusNotEnabled, err)
+	}
+}
@@ -0,0 +1,27 @@
+package nginx
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+const (
+	authDir = "auth"
+)
+
+// basicAuth returns the htpasswd data from the secret store or
+// BasicAuthPath
+func (p *NginxLoadBalancer) basicAuth(src string, secret bool) ([]byte, error) {
+	if secret {
+		return p.secret(src)
+	}
+
+	f, err := utils.ConfinedPath(p.cfg.BasicAuthPath, src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid htpasswd file (BasicAuthPath): %s", err)
+	}
+
+	return ioutil.ReadFile(f)
+}
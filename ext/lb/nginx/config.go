@@ -2,10 +2,14 @@ package nginx
 
 import (
 	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext/lb/utils"
 )
 
 type Server struct {
-	Addr string
+	ID        string
+	Container string
+	Addr      string
+	Drain     bool
 }
 
 type Upstream struct {
@@ -32,9 +36,27 @@ type Host struct {
 	Upstream           *Upstream
 	WebsocketEndpoints []string
 	IPHash             bool
+	StickyCookie       string
+	StickyKey          string
+	HealthCheck        *utils.HealthCheckSpec
+	LimitZone          string
+	LimitKey           string
+	RateLimit          string
+	ConnLimit          int
+	Allow              []string
+	Deny               []string
+	BasicAuth          string
+	RequestHeaders     []*utils.Header
+	ResponseHeaders    []*utils.Header
+	HTTP2              bool
+	GRPC               bool
+	Maintenance        bool
+	ErrorPages         []*utils.ErrorPage
 }
 type Config struct {
 	Hosts    []*Host
 	Config   *config.ExtensionConfig
 	Networks map[string]string
+	Files    []*utils.ProxyFile
+	Routes   []*utils.Route
 }
@@ -0,0 +1,35 @@
+package nginx
+
+import (
+	"fmt"
+	"path"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+const (
+	errorPagesDir = "errors"
+)
+
+// errorPages returns the custom error pages for the domain and adds the
+// pages to the files copied to the proxy containers
+func (p *NginxLoadBalancer) errorPages(config *ctypes.Config, domain string, files map[string]*utils.ProxyFile) ([]*utils.ErrorPage, error) {
+	pages, err := utils.ErrorPages(config, domain, p.cfg.ErrorPagesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, page := range pages {
+		name := path.Join(errorPagesDir, domain, fmt.Sprintf("%d.html", page.Code))
+		// nginx workers read the pages on each request
+		files[name] = &utils.ProxyFile{
+			Name: name,
+			Mode: 0644,
+			Data: page.Data,
+		}
+		page.Path = path.Join(p.cfg.ConfigBasePath, name)
+	}
+
+	return pages, nil
+}
@@ -2,10 +2,14 @@ package nginx
 
 import (
 	"fmt"
+	"path"
 	"path/filepath"
 	"strings"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/ext/lb/health"
+	"github.com/ehazlett/interlock/ext/lb/secrets"
 	"github.com/ehazlett/interlock/ext/lb/utils"
 	"golang.org/x/net/context"
 )
@@ -13,6 +17,9 @@ import (
 func (p *NginxLoadBalancer) GenerateProxyConfig(containers []types.Container) (interface{}, error) {
 	var hosts []*Host
 	upstreamServers := map[string][]string{}
+	drainServers := map[string]bool{}
+	serverIDs := map[string]string{}
+	serverContainers := map[string]string{}
 	serverNames := map[string][]string{}
 	hostContextRoots := map[string]*ContextRoot{}
 	hostContextRootRewrites := map[string]bool{}
@@ -23,14 +30,44 @@ func (p *NginxLoadBalancer) GenerateProxyConfig(containers []types.Container) (i
 	hostSSLBackend := map[string]bool{}
 	hostWebsocketEndpoints := map[string][]string{}
 	hostIPHash := map[string]bool{}
+	hostStickyCookie := map[string]string{}
+	hostHealthChecks := map[string]*utils.HealthCheckSpec{}
+	hostRateLimit := map[string]string{}
+	hostConnLimit := map[string]int{}
+	hostLimitKey := map[string]string{}
 	networks := map[string]string{}
+	staticRoutes := []*utils.Route{}
+	authFailed := map[string]bool{}
+	hostAllow := map[string][]string{}
+	hostDeny := map[string][]string{}
+	hostBasicAuth := map[string]string{}
+	hostRequestHeaders := map[string][]*utils.Header{}
+	hostResponseHeaders := map[string][]*utils.Header{}
+	hostProtocol := map[string]string{}
+	hostErrorPages := map[string][]*utils.ErrorPage{}
+	proxyFiles := map[string]*utils.ProxyFile{}
+
+	// failing upstreams are probed again below
+	p.prober.Reset()
+
+	// domains in maintenance from the management api and labels
+	maintenanceDomains := map[string]bool{}
+	for _, d := range p.maintenance.Domains() {
+		maintenanceDomains[d] = true
+	}
 
 	for _, c := range containers {
 		cntId := c.ID[:12]
 		// load interlock data
 		cInfo, err := p.client.ContainerInspect(context.Background(), c.ID)
 		if err != nil {
-			log().Errorf("unable to inspect container for upstream: %s", err)
+			p.reloadLog().Errorf("unable to inspect container for upstream: %s", err)
+			continue
+		}
+
+		// drained containers are removed from the config until they stop
+		if p.drains.Drained(cInfo.ID) {
+			p.reloadLog().WithField("container_id", cntId).Debug("container drained; excluding")
 			continue
 		}
 
@@ -55,6 +92,41 @@ func (p *NginxLoadBalancer) GenerateProxyConfig(containers []types.Container) (i
 			}
 		}
 
+		// marker containers for redirects and static responses
+		route, err := utils.StaticRoute(cInfo.Config, domain)
+		if err == nil && route != nil {
+			err = utils.NginxRoutes([]*utils.Route{route})
+		}
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing route: %s", err)
+			continue
+		}
+
+		if route != nil {
+			if cInfo.State != nil && cInfo.State.Running && contextRoot == "" {
+				p.reloadLog().WithField("domain", domain).Infof("route: redirect=%s code=%d", route.Redirect, route.Code)
+				staticRoutes = append(staticRoutes, route)
+			}
+			continue
+		}
+
+		if contextRoot == "" {
+			if utils.MaintenanceEnabled(cInfo.Config) && cInfo.State != nil && cInfo.State.Running {
+				p.reloadLog().WithField("domain", domain).Info("maintenance mode enabled")
+				maintenanceDomains[domain] = true
+			}
+
+			// custom error pages
+			if _, ok := hostErrorPages[domain]; !ok {
+				pages, err := p.errorPages(cInfo.Config, domain, proxyFiles)
+				if err != nil {
+					p.reloadLog().WithField("domain", domain).Errorf("error loading error pages: %s", err)
+				} else {
+					hostErrorPages[domain] = pages
+				}
+			}
+		}
+
 		hostContextRoots[domain] = &ContextRoot{
 			Name: contextRootName,
 			Path: contextRoot,
@@ -70,6 +142,46 @@ func (p *NginxLoadBalancer) GenerateProxyConfig(containers []types.Container) (i
 		hostSSL[domain] = utils.SSLEnabled(cInfo.Config)
 		hostSSLOnly[domain] = utils.SSLOnly(cInfo.Config)
 		hostIPHash[domain] = utils.IPHash(cInfo.Config)
+
+		// upstream health checks
+		healthCheck, err := utils.HealthCheckConfig(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing health check: %s", err)
+			continue
+		}
+
+		if healthCheck != nil {
+			hostHealthChecks[domain] = healthCheck
+		}
+
+		// sticky sessions
+		stickyCookie, err := utils.StickyCookie(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing sticky sessions: %s", err)
+		} else if stickyCookie != "" {
+			if hostIPHash[domain] {
+				p.reloadLog().WithField("domain", domain).Warn("ip hash is ignored with sticky sessions")
+				hostIPHash[domain] = false
+			}
+			hostStickyCookie[domain] = stickyCookie
+		}
+
+		// rate and connection limits
+		rateLimit, err := utils.RateLimitConfig(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing rate limit: %s", err)
+		} else if rateLimit != nil {
+			hostRateLimit[domain] = rateLimit.String()
+		}
+
+		connLimit, err := utils.ConnLimit(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing connection limit: %s", err)
+		} else if connLimit > 0 {
+			hostConnLimit[domain] = connLimit
+		}
+
+		hostLimitKey[domain] = limitKey(utils.LimitKeyHeader(cInfo.Config))
 		// check ssl backend
 		hostSSLBackend[domain] = utils.SSLBackend(cInfo.Config)
 
@@ -80,60 +192,150 @@ func (p *NginxLoadBalancer) GenerateProxyConfig(containers []types.Container) (i
 
 		if certName != "" {
 			certPath := filepath.Join(baseCertPath, certName)
-			log().Infof("ssl cert for %s: %s", domain, certPath)
+			p.reloadLog().WithField("domain", domain).Infof("ssl cert: %s", certPath)
 			hostSSLCert[domain] = certPath
 		}
 
 		certKeyName := utils.SSLCertKey(cInfo.Config)
 		if certKeyName != "" {
 			keyPath := filepath.Join(baseCertPath, certKeyName)
-			log().Infof("ssl key for %s: %s", domain, keyPath)
+			p.reloadLog().WithField("domain", domain).Infof("ssl key: %s", keyPath)
 			hostSSLCertKey[domain] = keyPath
 		}
 
-		addr := ""
-
-		// check for networking
-		if n, ok := utils.OverlayEnabled(cInfo.Config); ok {
-			log().Debugf("configuring docker network: name=%s", n)
-
-			network, err := p.client.NetworkInspect(context.Background(), n)
-			if err != nil {
-				log().Error(err)
-				continue
+		// ssl secrets contain both the cert and key
+		if secretKey := utils.SSLSecret(cInfo.Config); secretKey != "" {
+			secretName := path.Join(secretsDir, secrets.FileName(secretKey))
+			if _, ok := proxyFiles[secretName]; !ok {
+				data, err := p.secret(secretKey)
+				if err != nil {
+					p.reloadLog().WithField("domain", domain).Errorf("error loading ssl secret: %s", err)
+				} else {
+					proxyFiles[secretName] = &utils.ProxyFile{
+						Name: secretName,
+						Mode: 0600,
+						Data: data,
+					}
+				}
 			}
 
-			addr, err = utils.BackendOverlayAddress(network, cInfo)
-			if err != nil {
-				log().Error(err)
-				continue
+			if _, ok := proxyFiles[secretName]; ok {
+				secretPath := path.Join(p.cfg.ConfigBasePath, secretName)
+				p.reloadLog().WithField("domain", domain).Infof("ssl secret: %s", secretKey)
+				hostSSLCert[domain] = secretPath
+				hostSSLCertKey[domain] = secretPath
 			}
+		}
 
-			networks[n] = ""
+		// upstream protocol
+		protocol, err := utils.Protocol(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing protocol: %s", err)
+			continue
+		}
+		hostProtocol[domain] = protocol
+
+		if protocol == utils.ProtocolH2 {
+			hostSSLBackend[domain] = true
+		}
+
+		if utils.HTTP2(protocol) && !hostSSL[domain] {
+			p.reloadLog().WithField("domain", domain).Warn("http/2 requires ssl to be enabled for nginx")
+		}
+
+		// custom headers
+		requestHeaders, err := utils.RequestHeaders(cInfo.Config)
+		if err == nil {
+			err = utils.NginxHeaders(requestHeaders)
+		}
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing request headers: %s", err)
 		} else {
-			portsExposed := false
-			for _, portBindings := range cInfo.NetworkSettings.Ports {
-				if len(portBindings) != 0 {
-					portsExposed = true
-					break
+			hostRequestHeaders[domain] = requestHeaders
+		}
+
+		responseHeaders, err := utils.ResponseHeaders(cInfo.Config)
+		if err == nil {
+			err = utils.NginxHeaders(responseHeaders)
+		}
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing response headers: %s", err)
+		} else {
+			hostResponseHeaders[domain] = responseHeaders
+		}
+
+		// access control
+		allow, err := utils.AllowList(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing allow list: %s", err)
+			continue
+		}
+		hostAllow[domain] = utils.MergeAddressLists(hostAllow[domain], allow)
+
+		deny, err := utils.DenyList(cInfo.Config)
+		if err != nil {
+			p.reloadLog().WithField("domain", domain).Errorf("error parsing deny list: %s", err)
+			continue
+		}
+		hostDeny[domain] = utils.MergeAddressLists(hostDeny[domain], deny)
+
+		if src, secret := utils.BasicAuth(cInfo.Config); src != "" {
+			authName := path.Join(authDir, fmt.Sprintf("%s.htpasswd", domain))
+			if _, ok := proxyFiles[authName]; !ok {
+				data, err := p.basicAuth(src, secret)
+				if err != nil {
+					// do not expose the service without auth
+					p.reloadLog().WithField("domain", domain).Errorf("error loading basic auth: %s", err)
+					authFailed[domain] = true
+					continue
+				}
+
+				// nginx workers read the htpasswd on each request
+				proxyFiles[authName] = &utils.ProxyFile{
+					Name: authName,
+					Mode: 0644,
+					Data: data,
 				}
-			}
-			if !portsExposed {
-				log().Warnf("%s: no ports exposed", cntId)
-				continue
 			}
 
-			addr, err = utils.BackendAddress(cInfo, p.cfg.BackendOverrideAddress)
-			if err != nil {
-				log().Error(err)
-				continue
+			hostBasicAuth[domain] = path.Join(p.cfg.ConfigBasePath, authName)
+		}
+
+		addr, network, err := p.backendAddr(cInfo)
+		if err != nil {
+			if err == errNoPortsExposed {
+				p.reloadLog().WithField("container_id", cntId).Warn("no ports exposed")
+			} else {
+				p.reloadLog().Error(err)
 			}
+			continue
+		}
+
+		if network != "" {
+			networks[network] = ""
+		}
+
+		// exclude containers that do not report healthy
+		if p.cfg.RequireHealthy && !health.DockerHealthy(cInfo.State) {
+			p.reloadLog().WithField("container_id", cntId).Infof("container not healthy; excluding: status=%s", health.DockerStatus(cInfo.State))
+			continue
+		}
+
+		// exclude upstreams that fail the probe
+		if !p.prober.Healthy(&health.Target{
+			ID:     cInfo.ID,
+			Addr:   addr,
+			SSL:    hostSSLBackend[domain],
+			Check:  healthCheck,
+			Status: health.DockerStatus(cInfo.State),
+		}) {
+			continue
 		}
 
 		// "parse" multiple labels for websocket endpoints
 		websocketEndpoints := utils.WebsocketEndpoints(cInfo.Config)
 
-		log().Debugf("websocket endpoints: %v", websocketEndpoints)
+		p.reloadLog().Debugf("websocket endpoints: %v", websocketEndpoints)
 
 		// websocket endpoints
 		for _, ws := range websocketEndpoints {
@@ -143,10 +345,10 @@ func (p *NginxLoadBalancer) GenerateProxyConfig(containers []types.Container) (i
 		// "parse" multiple labels for alias domains
 		aliasDomains := utils.AliasDomains(cInfo.Config)
 
-		log().Debugf("alias domains: %v", aliasDomains)
+		p.reloadLog().Debugf("alias domains: %v", aliasDomains)
 
 		for _, alias := range aliasDomains {
-			log().Debugf("adding alias %s for %s", alias, cntId)
+			p.reloadLog().WithField("container_id", cntId).Debugf("adding alias %s", alias)
 			serverNames[domain] = append(serverNames[domain], alias)
 			hostContextRoots[alias] = &ContextRoot{
 				Name: contextRootName,
@@ -154,9 +356,51 @@ func (p *NginxLoadBalancer) GenerateProxyConfig(containers []types.Container) (i
 			}
 		}
 
-		log().Infof("%s: upstream=%s", domain, addr)
+		p.reloadLog().WithFields(logrus.Fields{
+			"domain":       domain,
+			"container_id": cntId,
+			"upstream":     addr,
+		}).Info("adding upstream")
 
 		upstreamServers[domain] = append(upstreamServers[domain], addr)
+		serverIDs[addr] = cInfo.ID
+		serverContainers[addr] = strings.TrimPrefix(cInfo.Name, "/")
+		if utils.Drain(cInfo.Config) || p.drains.Draining(cInfo.ID) {
+			drainServers[addr] = true
+		}
+	}
+
+	// domains in maintenance without upstreams are still served
+	for d := range maintenanceDomains {
+		if _, ok := upstreamServers[d]; ok {
+			continue
+		}
+
+		if _, ok := hostContextRoots[d]; !ok {
+			hostContextRoots[d] = &ContextRoot{}
+		}
+
+		if _, ok := serverNames[d]; !ok {
+			serverNames[d] = []string{d}
+		}
+
+		if _, ok := hostErrorPages[d]; !ok {
+			pages, err := p.errorPages(nil, d, proxyFiles)
+			if err != nil {
+				p.reloadLog().WithField("domain", d).Errorf("error loading error pages: %s", err)
+			} else {
+				hostErrorPages[d] = pages
+			}
+		}
+
+		upstreamServers[d] = []string{}
+	}
+
+	// domains without auth are not served; the other upstreams of the
+	// domain would be exposed without auth otherwise
+	for d := range authFailed {
+		p.reloadLog().WithField("domain", d).Warn("basic auth failed to load; removing domain")
+		delete(upstreamServers, d)
 	}
 
 	for k, v := range upstreamServers {
@@ -173,13 +417,32 @@ func (p *NginxLoadBalancer) GenerateProxyConfig(containers []types.Container) (i
 			SSLBackend:         hostSSLBackend[k],
 			WebsocketEndpoints: hostWebsocketEndpoints[k],
 			IPHash:             hostIPHash[k],
+			StickyCookie:       hostStickyCookie[k],
+			HealthCheck:        hostHealthChecks[k],
+			StickyKey:          "sticky_" + strings.NewReplacer(".", "_", "-", "_").Replace(k),
+			LimitZone:          strings.Replace(k, ".", "_", -1),
+			LimitKey:           hostLimitKey[k],
+			RateLimit:          hostRateLimit[k],
+			ConnLimit:          hostConnLimit[k],
+			Allow:              hostAllow[k],
+			Deny:               hostDeny[k],
+			BasicAuth:          hostBasicAuth[k],
+			RequestHeaders:     hostRequestHeaders[k],
+			ResponseHeaders:    hostResponseHeaders[k],
+			HTTP2:              utils.HTTP2(hostProtocol[k]),
+			GRPC:               hostProtocol[k] == utils.ProtocolGRPC,
+			Maintenance:        maintenanceDomains[k],
+			ErrorPages:         hostErrorPages[k],
 		}
 
 		servers := []*Server{}
 
 		for _, s := range v {
 			srv := &Server{
-				Addr: s,
+				ID:        serverIDs[s],
+				Container: serverContainers[s],
+				Addr:      s,
+				Drain:     drainServers[s],
 			}
 
 			servers = append(servers, srv)
@@ -194,11 +457,63 @@ func (p *NginxLoadBalancer) GenerateProxyConfig(containers []types.Container) (i
 		hosts = append(hosts, h)
 	}
 
+	// static routes from the config and marker containers
+	configRoutes, err := utils.ConfigRoutes(p.cfg.Routes)
+	if err == nil {
+		err = utils.NginxRoutes(configRoutes)
+	}
+	if err != nil {
+		p.reloadLog().Errorf("error parsing routes: %s", err)
+	} else {
+		staticRoutes = append(configRoutes, staticRoutes...)
+	}
+
+	upstreamDomains := map[string]bool{}
+	for k := range upstreamServers {
+		for _, name := range serverNames[k] {
+			upstreamDomains[name] = true
+		}
+	}
+
+	files := []*utils.ProxyFile{}
+	for _, f := range proxyFiles {
+		files = append(files, f)
+	}
+
+	// upstream membership for nginx plus api updates
+	if p.upstreamAPIEnabled() {
+		p.setUpstreams(hosts, networks)
+	}
+
+	// domains and containers for the proxy stats
+	statsDomains := map[string]string{}
+	for _, h := range hosts {
+		name := h.Upstream.Name
+		if h.ContextRoot != nil && h.ContextRoot.Path != "" {
+			name = "ctx" + h.ContextRoot.Name
+		}
+		statsDomains[name] = h.Upstream.Name
+		statsDomains[name+"_backend"] = h.Upstream.Name
+	}
+	p.labels.Reset(statsDomains, serverContainers)
+
 	config := &Config{
 		Hosts:    hosts,
 		Config:   p.cfg,
 		Networks: networks,
+		Files:    files,
+		Routes:   utils.FilterRoutes(staticRoutes, upstreamDomains),
 	}
 
 	return config, nil
 }
+
+// limitKey returns the nginx variable to use as the key for rate and
+// connection limits
+func limitKey(header string) string {
+	if header == "" {
+		return "$binary_remote_addr"
+	}
+
+	return "$http_" + strings.Replace(strings.ToLower(header), "-", "_", -1)
+}
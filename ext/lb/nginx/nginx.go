@@ -9,6 +9,10 @@ import (
 	"github.com/docker/engine-api/types"
 	etypes "github.com/docker/engine-api/types/events"
 	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext/lb/health"
+	"github.com/ehazlett/interlock/ext/lb/secrets"
+	"github.com/ehazlett/interlock/ext/lb/stats"
+	"github.com/ehazlett/interlock/ext/lb/utils"
 	"golang.org/x/net/context"
 )
 
@@ -17,8 +21,15 @@ const (
 )
 
 type NginxLoadBalancer struct {
-	cfg    *config.ExtensionConfig
-	client *client.Client
+	cfg         *config.ExtensionConfig
+	client      *client.Client
+	secrets     *secrets.SecretStore
+	maintenance *utils.Maintenance
+	drains      *utils.Drains
+	prober      *health.Prober
+	upstreams   *upstreamState
+	labels      *stats.Labels
+	reloadID    string
 }
 
 func log() *logrus.Entry {
@@ -27,13 +38,33 @@ func log() *logrus.Entry {
 	})
 }
 
-func NewNginxLoadBalancer(c *config.ExtensionConfig, cl *client.Client) (*NginxLoadBalancer, error) {
+// SetReloadID sets the id of the reload in progress for the logs of the
+// config generation and proxy reload
+func (p *NginxLoadBalancer) SetReloadID(id string) {
+	p.reloadID = id
+}
+
+func (p *NginxLoadBalancer) reloadLog() *logrus.Entry {
+	if p.reloadID == "" {
+		return log()
+	}
+
+	return log().WithField("reload_id", p.reloadID)
+}
+
+func NewNginxLoadBalancer(c *config.ExtensionConfig, cl *client.Client, s *secrets.SecretStore, m *utils.Maintenance, d *utils.Drains, pr *health.Prober) (*NginxLoadBalancer, error) {
 	// parse config base dir
 	c.ConfigBasePath = filepath.Dir(c.ConfigPath)
 
 	lb := &NginxLoadBalancer{
-		cfg:    c,
-		client: cl,
+		cfg:         c,
+		client:      cl,
+		secrets:     s,
+		maintenance: m,
+		drains:      d,
+		prober:      pr,
+		upstreams:   newUpstreamState(),
+		labels:      stats.NewLabels(),
 	}
 
 	return lb, nil
@@ -71,15 +102,21 @@ func (p *NginxLoadBalancer) ConfigPath() string {
 
 func (p *NginxLoadBalancer) Reload(proxyContainers []types.Container) error {
 	// restart all interlock managed nginx containers
+	failed := map[string]error{}
 	for _, cnt := range proxyContainers {
 		// restart
-		log().Debugf("reloading proxy container: id=%s", cnt.ID)
+		p.reloadLog().WithField("container_id", cnt.ID).Debug("reloading proxy container")
 		if err := p.client.ContainerKill(context.Background(), cnt.ID, "HUP"); err != nil {
-			log().Errorf("error reloading container: id=%s err=%s", cnt.ID[:12], err)
+			p.reloadLog().WithField("container_id", cnt.ID).Errorf("error reloading container: err=%s", err)
+			failed[cnt.ID] = err
 			continue
 		}
 
-		log().Infof("restarted proxy container: id=%s name=%s", cnt.ID[:12], cnt.Names[0])
+		p.reloadLog().WithField("container_id", cnt.ID).Infof("restarted proxy container: name=%s", cnt.Names[0])
+	}
+
+	if len(failed) > 0 {
+		return &utils.ReloadError{Failed: failed}
 	}
 
 	return nil
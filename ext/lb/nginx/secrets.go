@@ -0,0 +1,19 @@
+package nginx
+
+import (
+	"fmt"
+)
+
+const (
+	secretsDir = "secrets"
+)
+
+// secret returns the tls material for the specified key from the
+// secret store
+func (p *NginxLoadBalancer) secret(key string) ([]byte, error) {
+	if p.secrets == nil {
+		return nil, fmt.Errorf("no secret store configured")
+	}
+
+	return p.secrets.Get(key)
+}
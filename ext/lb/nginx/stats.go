@@ -0,0 +1,190 @@
+package nginx
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ehazlett/interlock/ext/lb/stats"
+)
+
+const (
+	stubStatusPath = "/nginx_status"
+	plusStatusPath = "/status"
+)
+
+type plusStatusResponses struct {
+	Responses5xx float64 `json:"5xx"`
+}
+
+type plusStatusPeer struct {
+	Server    string              `json:"server"`
+	Active    float64             `json:"active"`
+	Requests  float64             `json:"requests"`
+	Responses plusStatusResponses `json:"responses"`
+}
+
+type plusStatusUpstream struct {
+	Peers []*plusStatusPeer `json:"peers"`
+	Queue struct {
+		Size float64 `json:"size"`
+	} `json:"queue"`
+}
+
+type plusStatusZone struct {
+	Processing float64             `json:"processing"`
+	Requests   float64             `json:"requests"`
+	Responses  plusStatusResponses `json:"responses"`
+}
+
+// plusStatus is the nginx plus status module response
+type plusStatus struct {
+	Connections struct {
+		Active float64 `json:"active"`
+	} `json:"connections"`
+	Requests struct {
+		Total float64 `json:"total"`
+	} `json:"requests"`
+	ServerZones map[string]*plusStatusZone     `json:"server_zones"`
+	Upstreams   map[string]*plusStatusUpstream `json:"upstreams"`
+}
+
+// Stats scrapes the traffic stats from the proxy at addr using the nginx
+// plus status module or stub_status
+func (p *NginxLoadBalancer) Stats(addr string) ([]*stats.Sample, error) {
+	path := stubStatusPath
+	if p.cfg.NginxPlusEnabled {
+		path = plusStatusPath
+	}
+
+	client := &http.Client{
+		Timeout: plusAPITimeout,
+	}
+
+	resp, err := client.Get(fmt.Sprintf("http://%s%s", addr, path))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from %s: %d", path, resp.StatusCode)
+	}
+
+	if p.cfg.NginxPlusEnabled {
+		return p.plusStatusSamples(resp.Body)
+	}
+
+	return stubStatusSamples(resp.Body)
+}
+
+// stubStatusSamples parses the stub_status page:
+//
+//	Active connections: 291
+//	server accepts handled requests
+//	 16630948 16630948 31070465
+//	Reading: 6 Writing: 179 Waiting: 106
+func stubStatusSamples(r io.Reader) ([]*stats.Sample, error) {
+	s := &stats.Sample{
+		Type: stats.TypeProxy,
+		Name: pluginName,
+	}
+
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSpace(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) < 3 || !strings.HasPrefix(lines[0], "Active connections:") {
+		return nil, fmt.Errorf("invalid stub_status response")
+	}
+
+	active, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(lines[0], "Active connections:")), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stub_status active connections: %s", err)
+	}
+	s.Active = active
+
+	counts := strings.Fields(lines[2])
+	if len(counts) != 3 {
+		return nil, fmt.Errorf("invalid stub_status counts: %s", lines[2])
+	}
+
+	requests, err := strconv.ParseFloat(counts[2], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stub_status requests: %s", err)
+	}
+	s.Requests = requests
+
+	return []*stats.Sample{s}, nil
+}
+
+// plusStatusSamples parses the nginx plus status module json
+func (p *NginxLoadBalancer) plusStatusSamples(r io.Reader) ([]*stats.Sample, error) {
+	var status plusStatus
+	if err := json.NewDecoder(r).Decode(&status); err != nil {
+		return nil, err
+	}
+
+	samples := []*stats.Sample{
+		{
+			Type:     stats.TypeProxy,
+			Name:     pluginName,
+			Requests: status.Requests.Total,
+			Active:   status.Connections.Active,
+		},
+	}
+
+	for name, zone := range status.ServerZones {
+		samples = append(samples, &stats.Sample{
+			Type:         stats.TypeFrontend,
+			Name:         name,
+			Domain:       p.labels.Domain(name),
+			Requests:     zone.Requests,
+			Responses5xx: zone.Responses.Responses5xx,
+			Active:       zone.Processing,
+		})
+	}
+
+	for name, upstream := range status.Upstreams {
+		backend := &stats.Sample{
+			Type:   stats.TypeBackend,
+			Name:   name,
+			Domain: p.labels.Domain(name),
+			Queue:  upstream.Queue.Size,
+		}
+
+		for _, peer := range upstream.Peers {
+			container := p.labels.Container(peer.Server)
+			if container == "" {
+				container = peer.Server
+			}
+
+			samples = append(samples, &stats.Sample{
+				Type:         stats.TypeServer,
+				Name:         name,
+				Domain:       backend.Domain,
+				Container:    container,
+				Requests:     peer.Requests,
+				Responses5xx: peer.Responses.Responses5xx,
+				Active:       peer.Active,
+			})
+
+			backend.Requests += peer.Requests
+			backend.Responses5xx += peer.Responses.Responses5xx
+			backend.Active += peer.Active
+		}
+
+		samples = append(samples, backend)
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,96 @@
+package nginx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext/lb/stats"
+)
+
+func TestStubStatusSamples(t *testing.T) {
+	data := `Active connections: 291 
+server accepts handled requests
+ 16630948 16630948 31070465 
+Reading: 6 Writing: 179 Waiting: 106 
+`
+
+	samples, err := stubStatusSamples(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) != 1 || samples[0].Active != 291 || samples[0].Requests != 31070465 {
+		t.Fatalf("unexpected samples: %+v", samples[0])
+	}
+}
+
+func TestStubStatusSamplesInvalid(t *testing.T) {
+	if _, err := stubStatusSamples(strings.NewReader("<html></html>")); err == nil {
+		t.Fatal("expected error for invalid stub_status")
+	}
+}
+
+func TestPlusStatusSamples(t *testing.T) {
+	data := `{
+  "connections": {"active": 4},
+  "requests": {"total": 100},
+  "server_zones": {
+    "app.example.com_backend": {"processing": 1, "requests": 80, "responses": {"5xx": 3}}
+  },
+  "upstreams": {
+    "app.example.com": {
+      "peers": [
+        {"server": "10.0.0.1:32768", "active": 2, "requests": 60, "responses": {"5xx": 2}},
+        {"server": "10.0.0.2:32768", "active": 1, "requests": 20, "responses": {"5xx": 1}}
+      ],
+      "queue": {"size": 5}
+    }
+  }
+}`
+
+	p := &NginxLoadBalancer{
+		cfg:    &config.ExtensionConfig{NginxPlusEnabled: true},
+		labels: stats.NewLabels(),
+	}
+	p.labels.Reset(
+		map[string]string{
+			"app.example.com":         "app.example.com",
+			"app.example.com_backend": "app.example.com",
+		},
+		map[string]string{"10.0.0.1:32768": "app1"},
+	)
+
+	samples, err := p.plusStatusSamples(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(samples) != 5 {
+		t.Fatalf("expected 5 samples; received %d", len(samples))
+	}
+
+	byContainer := map[string]*stats.Sample{}
+	var backend *stats.Sample
+	for _, s := range samples {
+		switch s.Type {
+		case stats.TypeServer:
+			byContainer[s.Container] = s
+		case stats.TypeBackend:
+			backend = s
+		}
+	}
+
+	if s := byContainer["app1"]; s == nil || s.Requests != 60 || s.Domain != "app.example.com" {
+		t.Fatalf("unexpected server sample: %+v", s)
+	}
+
+	// unknown servers are labelled with the address
+	if s := byContainer["10.0.0.2:32768"]; s == nil {
+		t.Fatal("expected sample for unknown server")
+	}
+
+	if backend == nil || backend.Requests != 80 || backend.Responses5xx != 3 || backend.Queue != 5 {
+		t.Fatalf("unexpected backend sample: %+v", backend)
+	}
+}
@@ -26,6 +26,11 @@ http {
 
     access_log  /var/log/nginx/access.log  main;
 
+    {{ if .Config.AccessLogEndpoint }}log_format  interlock  '$proxy_host $host $remote_addr [$time_local] "$request" '
+                           '$status $body_bytes_sent $request_time "$http_user_agent"';
+
+    access_log  syslog:server={{ .Config.AccessLogEndpoint }},tag=nginx  interlock;{{ end }}
+
     sendfile        on;
     #tcp_nopush     on;
 
@@ -73,6 +78,23 @@ http {
 	    location {{ $host.ContextRoot.Path }} {
 		{{ if $host.ContextRootRewrite }}rewrite ^([^.]*[^/])$ $1/ permanent;
 		rewrite  ^{{ $host.ContextRoot.Path }}/(.*)  /$1 break;{{ end }}
+		{{ range $addr := $host.Deny }}deny {{ $addr }};
+		{{ end }}{{ range $addr := $host.Allow }}allow {{ $addr }};
+		{{ end }}{{ if $host.Allow }}deny all;{{ end }}
+		{{ if $host.BasicAuth }}auth_basic "Restricted";
+		auth_basic_user_file {{ $host.BasicAuth }};{{ end }}
+		{{ if $host.RequestHeaders }}proxy_set_header X-Real-IP $remote_addr;
+		proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+		proxy_set_header X-Forwarded-Proto $proxy_x_forwarded_proto;
+		proxy_set_header Host $http_host;
+		{{ range $h := $host.RequestHeaders }}proxy_set_header {{ $h.Name }} {{ $h.Quoted }};
+		{{ end }}{{ end }}
+		{{ range $h := $host.ResponseHeaders }}add_header {{ $h.Name }} {{ $h.Quoted }} always;
+		{{ end }}
+		{{ if $host.RateLimit }}limit_req zone={{ $host.LimitZone }}_req;
+		limit_req_status 429;{{ end }}
+		{{ if $host.ConnLimit }}limit_conn {{ $host.LimitZone }}_conn {{ $host.ConnLimit }};
+		limit_conn_status 429;{{ end }}
 		proxy_pass http://ctx{{ $host.ContextRoot.Name }};
 	    }
 	    {{ end }}
@@ -84,31 +106,70 @@ http {
     }
 
     {{ range $host := .Hosts }}
+    {{ if $host.RateLimit }}limit_req_zone {{ $host.LimitKey }} zone={{ $host.LimitZone }}_req:10m rate={{ $host.RateLimit }};{{ end }}
+    {{ if $host.ConnLimit }}limit_conn_zone {{ $host.LimitKey }} zone={{ $host.LimitZone }}_conn:10m;{{ end }}
+    {{ if $host.StickyCookie }}map $cookie_{{ $host.StickyCookie }} ${{ $host.StickyKey }} {
+        ""      $request_id;
+        default $cookie_{{ $host.StickyCookie }};
+    }
+    map $cookie_{{ $host.StickyCookie }} ${{ $host.StickyKey }}_cookie {
+        ""      "{{ $host.StickyCookie }}=$request_id; Path=/; HttpOnly";
+        default "";
+    }{{ end }}
     {{ if ne $host.ContextRoot.Path "" }}
     upstream ctx{{ $host.ContextRoot.Name }} {
         zone ctx{{ $host.Upstream.Name }}_backend 64k;
 
-        {{ range $up := $host.Upstream.Servers }}server {{ $up.Addr }};
+        {{ range $up := $host.Upstream.Servers }}server {{ $up.Addr }}{{ with $host.HealthCheck }}{{ if .Fall }} max_fails={{ .Fall }}{{ end }} fail_timeout={{ .FailTimeout }}s{{ end }}{{ if $up.Drain }} down{{ end }};
         {{ end }}
     }{{ else }}
-    upstream {{ $host.Upstream.Name }} {
+    {{ if not $host.Maintenance }}upstream {{ $host.Upstream.Name }} {
         {{ if $host.IPHash }}ip_hash; {{else}}zone {{ $host.Upstream.Name }}_backend 64k;{{ end }}
+        {{ if $host.StickyCookie }}hash ${{ $host.StickyKey }} consistent;{{ end }}
 
-        {{ range $up := $host.Upstream.Servers }}server {{ $up.Addr }};
+        {{ range $up := $host.Upstream.Servers }}server {{ $up.Addr }}{{ with $host.HealthCheck }}{{ if .Fall }} max_fails={{ .Fall }}{{ end }} fail_timeout={{ .FailTimeout }}s{{ end }}{{ if $up.Drain }} down{{ end }};
         {{ end }}
-    }
+    }{{ end }}
     server {
         listen {{ $host.Port }};
 
         server_name{{ range $name := $host.ServerNames }} {{ $name }}{{ end }};
+        {{ range $addr := $host.Deny }}deny {{ $addr }};
+        {{ end }}{{ range $addr := $host.Allow }}allow {{ $addr }};
+        {{ end }}{{ if $host.Allow }}deny all;{{ end }}
+        {{ if $host.BasicAuth }}auth_basic "Restricted";
+        auth_basic_user_file {{ $host.BasicAuth }};{{ end }}
+        {{ if $host.RequestHeaders }}proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $proxy_x_forwarded_proto;
+        proxy_set_header Host $http_host;
+        {{ range $h := $host.RequestHeaders }}proxy_set_header {{ $h.Name }} {{ $h.Quoted }};
+        {{ end }}{{ end }}
+        {{ range $h := $host.ResponseHeaders }}add_header {{ $h.Name }} {{ $h.Quoted }} always;
+        {{ end }}{{ if $host.StickyCookie }}add_header Set-Cookie ${{ $host.StickyKey }}_cookie;
+        {{ end }}
+        {{ range $page := $host.ErrorPages }}error_page {{ $page.Code }} /_interlock_error_{{ $page.Code }}.html;
+        location = /_interlock_error_{{ $page.Code }}.html {
+            internal;
+            alias {{ $page.Path }};
+        }
+        {{ end }}
         {{ if $host.SSLOnly }}return 302 https://$server_name$request_uri;{{ else }}
         location / {
-            {{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}
+            {{ if $host.RateLimit }}limit_req zone={{ $host.LimitZone }}_req;
+            limit_req_status 429;{{ end }}
+            {{ if $host.ConnLimit }}limit_conn {{ $host.LimitZone }}_conn {{ $host.ConnLimit }};
+            limit_conn_status 429;{{ end }}
+            {{ if $host.Maintenance }}return 503;{{ else if $host.GRPC }}{{ if $host.SSLBackend }}grpc_pass grpcs://{{ $host.Upstream.Name }};{{ else }}grpc_pass grpc://{{ $host.Upstream.Name }};{{ end }}{{ else }}{{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}{{ end }}
         }
 
         {{ range $ws := $host.WebsocketEndpoints }}
         location {{ $ws }} {
-            {{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}
+            {{ if $host.RateLimit }}limit_req zone={{ $host.LimitZone }}_req;
+            limit_req_status 429;{{ end }}
+            {{ if $host.ConnLimit }}limit_conn {{ $host.LimitZone }}_conn {{ $host.ConnLimit }};
+            limit_conn_status 429;{{ end }}
+            {{ if $host.Maintenance }}return 503;{{ else }}{{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}{{ end }}
             proxy_http_version 1.1;
             proxy_set_header Upgrade $http_upgrade;
             proxy_set_header Connection $connection_upgrade;
@@ -124,19 +185,47 @@ http {
     }
     {{ if $host.SSL }}
     server {
-        listen {{ $host.SSLPort }};
+        listen {{ $host.SSLPort }}{{ if $host.HTTP2 }} ssl http2{{ end }};
         ssl on;
         ssl_certificate {{ $host.SSLCert }};
         ssl_certificate_key {{ $host.SSLCertKey }};
         server_name{{ range $name := $host.ServerNames }} {{ $name }}{{ end }};
+        {{ range $addr := $host.Deny }}deny {{ $addr }};
+        {{ end }}{{ range $addr := $host.Allow }}allow {{ $addr }};
+        {{ end }}{{ if $host.Allow }}deny all;{{ end }}
+        {{ if $host.BasicAuth }}auth_basic "Restricted";
+        auth_basic_user_file {{ $host.BasicAuth }};{{ end }}
+        {{ if $host.RequestHeaders }}proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $proxy_x_forwarded_proto;
+        proxy_set_header Host $http_host;
+        {{ range $h := $host.RequestHeaders }}proxy_set_header {{ $h.Name }} {{ $h.Quoted }};
+        {{ end }}{{ end }}
+        {{ range $h := $host.ResponseHeaders }}add_header {{ $h.Name }} {{ $h.Quoted }} always;
+        {{ end }}{{ if $host.StickyCookie }}add_header Set-Cookie ${{ $host.StickyKey }}_cookie;
+        {{ end }}
+        {{ range $page := $host.ErrorPages }}error_page {{ $page.Code }} /_interlock_error_{{ $page.Code }}.html;
+        location = /_interlock_error_{{ $page.Code }}.html {
+            internal;
+            alias {{ $page.Path }};
+        }
+        {{ end }}
 
         location / {
-            {{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}
+            {{ if $host.RateLimit }}limit_req zone={{ $host.LimitZone }}_req;
+            limit_req_status 429;{{ end }}
+            {{ if $host.ConnLimit }}limit_conn {{ $host.LimitZone }}_conn {{ $host.ConnLimit }};
+            limit_conn_status 429;{{ end }}
+            {{ if $host.Maintenance }}return 503;{{ else if $host.GRPC }}{{ if $host.SSLBackend }}grpc_pass grpcs://{{ $host.Upstream.Name }};{{ else }}grpc_pass grpc://{{ $host.Upstream.Name }};{{ end }}{{ else }}{{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}{{ end }}
         }
 
         {{ range $ws := $host.WebsocketEndpoints }}
         location {{ $ws }} {
-            {{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}
+            {{ if $host.RateLimit }}limit_req zone={{ $host.LimitZone }}_req;
+            limit_req_status 429;{{ end }}
+            {{ if $host.ConnLimit }}limit_conn {{ $host.LimitZone }}_conn {{ $host.ConnLimit }};
+            limit_conn_status 429;{{ end }}
+            {{ if $host.Maintenance }}return 503;{{ else }}{{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}{{ end }}
             proxy_http_version 1.1;
             proxy_set_header Upgrade $http_upgrade;
             proxy_set_header Connection $connection_upgrade;
@@ -153,6 +242,15 @@ http {
     {{ end }} {{/* end context root */}}
     {{ end }} {{/* end host range */}}
 
+    {{ range $route := .Routes }}
+    server {
+        listen {{ $.Config.Port }};
+        server_name {{ $route.Domain }};
+        {{ if $route.Redirect }}return {{ $route.Code }} {{ $route.Redirect }}{{ if $route.PreservePath }}$request_uri{{ end }};{{ else }}default_type text/plain;
+        return {{ $route.Code }} {{ $route.QuotedBody }};{{ end }}
+    }
+    {{ end }} {{/* end route range */}}
+
     include {{ .Config.ConfigBasePath }}/conf.d/*.conf;
 }
 `
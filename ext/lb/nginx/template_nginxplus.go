@@ -26,6 +26,11 @@ http {
 
     access_log  /var/log/nginx/access.log  main;
 
+    {{ if .Config.AccessLogEndpoint }}log_format  interlock  '$proxy_host $host $remote_addr [$time_local] "$request" '
+                           '$status $body_bytes_sent $request_time "$http_user_agent"';
+
+    access_log  syslog:server={{ .Config.AccessLogEndpoint }},tag=nginx  interlock;{{ end }}
+
     sendfile        on;
     #tcp_nopush     on;
 
@@ -81,40 +86,109 @@ http {
 	    location {{ $host.ContextRoot.Path }} {
 		{{ if $host.ContextRootRewrite }}rewrite ^([^.]*[^/])$ $1/ permanent;
 		rewrite  ^{{ $host.ContextRoot.Path }}/(.*)  /$1 break;{{ end }}
+		{{ range $addr := $host.Deny }}deny {{ $addr }};
+		{{ end }}{{ range $addr := $host.Allow }}allow {{ $addr }};
+		{{ end }}{{ if $host.Allow }}deny all;{{ end }}
+		{{ if $host.BasicAuth }}auth_basic "Restricted";
+		auth_basic_user_file {{ $host.BasicAuth }};{{ end }}
+		{{ if $host.RequestHeaders }}proxy_set_header X-Real-IP $remote_addr;
+		proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+		proxy_set_header X-Forwarded-Proto $proxy_x_forwarded_proto;
+		proxy_set_header Host $http_host;
+		{{ range $h := $host.RequestHeaders }}proxy_set_header {{ $h.Name }} {{ $h.Quoted }};
+		{{ end }}{{ end }}
+		{{ range $h := $host.ResponseHeaders }}add_header {{ $h.Name }} {{ $h.Quoted }} always;
+		{{ end }}
+		{{ if $host.RateLimit }}limit_req zone={{ $host.LimitZone }}_req;
+		limit_req_status 429;{{ end }}
+		{{ if $host.ConnLimit }}limit_conn {{ $host.LimitZone }}_conn {{ $host.ConnLimit }};
+		limit_conn_status 429;{{ end }}
 		proxy_pass http://ctx{{ $host.ContextRoot.Name }};
 	    }
 	    {{ end }}
 	    {{ end }}
     }
 
+    {{ if .Config.RuntimeAPIPort }}# nginx plus api
+    server {
+        listen {{ .Config.RuntimeAPIPort }};
+
+        location /api {
+            {{ range $addr := .Config.RuntimeAPIClients }}allow {{ $addr }};
+            {{ end }}deny all;
+            api write=on;
+        }
+    }{{ end }}
+
     {{ range $host := .Hosts }}
+    {{ if $host.RateLimit }}limit_req_zone {{ $host.LimitKey }} zone={{ $host.LimitZone }}_req:10m rate={{ $host.RateLimit }};{{ end }}
+    {{ if $host.ConnLimit }}limit_conn_zone {{ $host.LimitKey }} zone={{ $host.LimitZone }}_conn:10m;{{ end }}
     {{ if ne $host.ContextRoot.Path "" }}
     upstream ctx{{ $host.ContextRoot.Name }} {
         zone ctx{{ $host.Upstream.Name }}_backend 64k;
 
-        {{ range $up := $host.Upstream.Servers }}server {{ $up.Addr }};
+        {{ range $up := $host.Upstream.Servers }}server {{ $up.Addr }}{{ if $up.Drain }} drain{{ end }};
         {{ end }}
     }{{ else }}
+    {{ if not $host.Maintenance }}{{ with $host.HealthCheck }}{{ if .Status }}match {{ $host.Upstream.Name }}_health_check {
+        status {{ .Status }};
+    }{{ end }}{{ end }}
     upstream {{ $host.Upstream.Name }} {
-        {{ if $host.IPHash }}ip_hash; {{else}}zone {{ $host.Upstream.Name }}_backend 64k;{{ end }}
+        {{ if $host.IPHash }}ip_hash;
+        {{ end }}zone {{ $host.Upstream.Name }}_backend 64k;
+        {{ if $host.StickyCookie }}sticky cookie {{ $host.StickyCookie }};{{ end }}
 
-        {{ range $up := $host.Upstream.Servers }}server {{ $up.Addr }};
+        {{ range $up := $host.Upstream.Servers }}server {{ $up.Addr }}{{ if $up.Drain }} drain{{ end }};
         {{ end }}
-    }
+    }{{ end }}
     server {
         listen {{ $host.Port }};
 
         server_name{{ range $name := $host.ServerNames }} {{ $name }}{{ end }};
+        {{ range $addr := $host.Deny }}deny {{ $addr }};
+        {{ end }}{{ range $addr := $host.Allow }}allow {{ $addr }};
+        {{ end }}{{ if $host.Allow }}deny all;{{ end }}
+        {{ if $host.BasicAuth }}auth_basic "Restricted";
+        auth_basic_user_file {{ $host.BasicAuth }};{{ end }}
+        {{ if $host.RequestHeaders }}proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $proxy_x_forwarded_proto;
+        proxy_set_header Host $http_host;
+        {{ range $h := $host.RequestHeaders }}proxy_set_header {{ $h.Name }} {{ $h.Quoted }};
+        {{ end }}{{ end }}
+        {{ range $h := $host.ResponseHeaders }}add_header {{ $h.Name }} {{ $h.Quoted }} always;
+        {{ end }}
+        {{ range $page := $host.ErrorPages }}error_page {{ $page.Code }} /_interlock_error_{{ $page.Code }}.html;
+        location = /_interlock_error_{{ $page.Code }}.html {
+            internal;
+            alias {{ $page.Path }};
+        }
+        {{ end }}
         {{ if $host.SSLOnly }}return 302 https://$server_name$request_uri;{{ else }}
         location / {
-            {{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}
+            {{ if $host.RateLimit }}limit_req zone={{ $host.LimitZone }}_req;
+            limit_req_status 429;{{ end }}
+            {{ if $host.ConnLimit }}limit_conn {{ $host.LimitZone }}_conn {{ $host.ConnLimit }};
+            limit_conn_status 429;{{ end }}
+            {{ if $host.Maintenance }}return 503;{{ else if $host.GRPC }}{{ if $host.SSLBackend }}grpc_pass grpcs://{{ $host.Upstream.Name }};{{ else }}grpc_pass grpc://{{ $host.Upstream.Name }};{{ end }}{{ else }}{{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}{{ end }}
         }
 
         status_zone {{ $host.Upstream.Name }}_backend;
 
+        {{ if not $host.Maintenance }}{{ with $host.HealthCheck }}location @{{ $host.Upstream.Name }}_health_check {
+            {{ if .Timeout }}proxy_connect_timeout {{ .Timeout }}ms;
+            proxy_read_timeout {{ .Timeout }}ms;{{ end }}
+            {{ if $host.GRPC }}{{ if $host.SSLBackend }}grpc_pass grpcs://{{ $host.Upstream.Name }};{{ else }}grpc_pass grpc://{{ $host.Upstream.Name }};{{ end }}{{ else }}{{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}{{ end }}
+            health_check interval={{ .Interval }}ms{{ if .Fall }} fails={{ .Fall }}{{ end }}{{ if .Rise }} passes={{ .Rise }}{{ end }}{{ if .HTTP }} uri={{ .Path }}{{ end }}{{ if .Status }} match={{ $host.Upstream.Name }}_health_check{{ end }}{{ if $host.GRPC }} type=grpc{{ end }};
+        }{{ end }}{{ end }}
+
         {{ range $ws := $host.WebsocketEndpoints }}
         location {{ $ws }} {
-            {{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}
+            {{ if $host.RateLimit }}limit_req zone={{ $host.LimitZone }}_req;
+            limit_req_status 429;{{ end }}
+            {{ if $host.ConnLimit }}limit_conn {{ $host.LimitZone }}_conn {{ $host.ConnLimit }};
+            limit_conn_status 429;{{ end }}
+            {{ if $host.Maintenance }}return 503;{{ else }}{{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}{{ end }}
             proxy_http_version 1.1;
             proxy_set_header Upgrade $http_upgrade;
             proxy_set_header Connection $connection_upgrade;
@@ -129,19 +203,53 @@ http {
     }
     {{ if $host.SSL }}
     server {
-        listen {{ $host.SSLPort }};
+        listen {{ $host.SSLPort }}{{ if $host.HTTP2 }} ssl http2{{ end }};
         ssl on;
         ssl_certificate {{ $host.SSLCert }};
         ssl_certificate_key {{ $host.SSLCertKey }};
         server_name{{ range $name := $host.ServerNames }} {{ $name }}{{ end }};
+        {{ range $addr := $host.Deny }}deny {{ $addr }};
+        {{ end }}{{ range $addr := $host.Allow }}allow {{ $addr }};
+        {{ end }}{{ if $host.Allow }}deny all;{{ end }}
+        {{ if $host.BasicAuth }}auth_basic "Restricted";
+        auth_basic_user_file {{ $host.BasicAuth }};{{ end }}
+        {{ if $host.RequestHeaders }}proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $proxy_x_forwarded_proto;
+        proxy_set_header Host $http_host;
+        {{ range $h := $host.RequestHeaders }}proxy_set_header {{ $h.Name }} {{ $h.Quoted }};
+        {{ end }}{{ end }}
+        {{ range $h := $host.ResponseHeaders }}add_header {{ $h.Name }} {{ $h.Quoted }} always;
+        {{ end }}
+        {{ range $page := $host.ErrorPages }}error_page {{ $page.Code }} /_interlock_error_{{ $page.Code }}.html;
+        location = /_interlock_error_{{ $page.Code }}.html {
+            internal;
+            alias {{ $page.Path }};
+        }
+        {{ end }}
 
         location / {
-            {{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}
+            {{ if $host.RateLimit }}limit_req zone={{ $host.LimitZone }}_req;
+            limit_req_status 429;{{ end }}
+            {{ if $host.ConnLimit }}limit_conn {{ $host.LimitZone }}_conn {{ $host.ConnLimit }};
+            limit_conn_status 429;{{ end }}
+            {{ if $host.Maintenance }}return 503;{{ else if $host.GRPC }}{{ if $host.SSLBackend }}grpc_pass grpcs://{{ $host.Upstream.Name }};{{ else }}grpc_pass grpc://{{ $host.Upstream.Name }};{{ end }}{{ else }}{{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}{{ end }}
         }
 
+        {{ if and $host.SSLOnly (not $host.Maintenance) }}{{ with $host.HealthCheck }}location @{{ $host.Upstream.Name }}_health_check {
+            {{ if .Timeout }}proxy_connect_timeout {{ .Timeout }}ms;
+            proxy_read_timeout {{ .Timeout }}ms;{{ end }}
+            {{ if $host.GRPC }}{{ if $host.SSLBackend }}grpc_pass grpcs://{{ $host.Upstream.Name }};{{ else }}grpc_pass grpc://{{ $host.Upstream.Name }};{{ end }}{{ else }}{{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}{{ end }}
+            health_check interval={{ .Interval }}ms{{ if .Fall }} fails={{ .Fall }}{{ end }}{{ if .Rise }} passes={{ .Rise }}{{ end }}{{ if .HTTP }} uri={{ .Path }}{{ end }}{{ if .Status }} match={{ $host.Upstream.Name }}_health_check{{ end }}{{ if $host.GRPC }} type=grpc{{ end }};
+        }{{ end }}{{ end }}
+
         {{ range $ws := $host.WebsocketEndpoints }}
         location {{ $ws }} {
-            {{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}
+            {{ if $host.RateLimit }}limit_req zone={{ $host.LimitZone }}_req;
+            limit_req_status 429;{{ end }}
+            {{ if $host.ConnLimit }}limit_conn {{ $host.LimitZone }}_conn {{ $host.ConnLimit }};
+            limit_conn_status 429;{{ end }}
+            {{ if $host.Maintenance }}return 503;{{ else }}{{ if $host.SSLBackend }}proxy_pass https://{{ $host.Upstream.Name }};{{ else }}proxy_pass http://{{ $host.Upstream.Name }};{{ end }}{{ end }}
             proxy_http_version 1.1;
             proxy_set_header Upgrade $http_upgrade;
             proxy_set_header Connection $connection_upgrade;
@@ -158,6 +266,15 @@ http {
     {{ end }} {{/* end context root */}}
     {{ end }} {{/* end host range */}}
 
+    {{ range $route := .Routes }}
+    server {
+        listen {{ $.Config.Port }};
+        server_name {{ $route.Domain }};
+        {{ if $route.Redirect }}return {{ $route.Code }} {{ $route.Redirect }}{{ if $route.PreservePath }}$request_uri{{ end }};{{ else }}default_type text/plain;
+        return {{ $route.Code }} {{ $route.QuotedBody }};{{ end }}
+    }
+    {{ end }} {{/* end route range */}}
+
     include {{ .Config.ConfigBasePath }}/conf.d/*.conf;
 }
 `
@@ -0,0 +1,227 @@
+package nginx
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/ext/lb/health"
+	"github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+// plusServer is an upstream server in the nginx plus api
+type plusServer struct {
+	ID     int    `json:"id,omitempty"`
+	Server string `json:"server"`
+	Drain  bool   `json:"drain,omitempty"`
+}
+
+// upstreamState tracks the upstream servers in the generated config so
+// servers can be added and removed through the nginx plus api without a
+// reload
+type upstreamState struct {
+	lock      *sync.Mutex
+	upstreams map[string]map[string]string // upstream -> server addr -> container id
+	networks  map[string]bool
+	removed   map[string]bool
+}
+
+func newUpstreamState() *upstreamState {
+	return &upstreamState{
+		lock:      &sync.Mutex{},
+		upstreams: map[string]map[string]string{},
+		networks:  map[string]bool{},
+		removed:   map[string]bool{},
+	}
+}
+
+// upstreamAPIEnabled returns true if upstreams are updated through the
+// nginx plus api
+func (p *NginxLoadBalancer) upstreamAPIEnabled() bool {
+	return p.cfg.NginxPlusEnabled && p.cfg.RuntimeAPIPort > 0
+}
+
+// setUpstreams resets the upstream state to the generated config
+func (p *NginxLoadBalancer) setUpstreams(hosts []*Host, networks map[string]string) {
+	s := newUpstreamState()
+
+	for n := range networks {
+		s.networks[n] = true
+	}
+
+	for _, h := range hosts {
+		// maintenance hosts have no upstream
+		if h.Maintenance {
+			continue
+		}
+
+		name := h.Upstream.Name
+		if h.ContextRoot != nil && h.ContextRoot.Path != "" {
+			name = "ctx" + h.ContextRoot.Name
+		}
+
+		servers := map[string]string{}
+		for _, srv := range h.Upstream.Servers {
+			servers[srv.Addr] = srv.ID
+		}
+
+		s.upstreams[name] = servers
+	}
+
+	p.upstreams.lock.Lock()
+	defer p.upstreams.lock.Unlock()
+
+	p.upstreams.upstreams = s.upstreams
+	p.upstreams.networks = s.networks
+	p.upstreams.removed = s.removed
+}
+
+// AddServer adds the container to its upstream on the proxies with the
+// nginx plus api at proxyAddrs.  It returns false if a reload is required
+// (i.e. a new host).
+func (p *NginxLoadBalancer) AddServer(proxyAddrs []string, cInfo types.ContainerJSON) (bool, error) {
+	if !p.upstreamAPIEnabled() {
+		return false, nil
+	}
+
+	if utils.ServiceDomain(cInfo.Config) == "" {
+		return false, nil
+	}
+
+	addr, network, err := p.backendAddr(cInfo)
+	if err != nil {
+		return false, err
+	}
+
+	if p.cfg.RequireHealthy && !health.DockerHealthy(cInfo.State) {
+		// added on the health_status event reload
+		log().WithField("container_id", cInfo.ID).Info("container not healthy; not adding server")
+		return true, nil
+	}
+
+	healthCheck, err := utils.HealthCheckConfig(cInfo.Config)
+	if err != nil {
+		return false, err
+	}
+
+	if !p.prober.Healthy(&health.Target{
+		ID:     cInfo.ID,
+		Addr:   addr,
+		SSL:    utils.SSLBackend(cInfo.Config),
+		Check:  healthCheck,
+		Status: health.DockerStatus(cInfo.State),
+	}) {
+		// added on the reload once the upstream recovers
+		return true, nil
+	}
+
+	name := upstreamName(cInfo)
+
+	p.upstreams.lock.Lock()
+	defer p.upstreams.lock.Unlock()
+
+	if network != "" && !p.upstreams.networks[network] {
+		log().WithField("network", network).Debug("proxy not connected to network; reload required")
+		return false, nil
+	}
+
+	servers, ok := p.upstreams.upstreams[name]
+	if !ok {
+		log().Debugf("new upstream; reload required: upstream=%s", name)
+		return false, nil
+	}
+
+	// already in the generated config
+	if id, ok := servers[addr]; ok && id == cInfo.ID && !p.upstreams.removed[cInfo.ID] {
+		return true, nil
+	}
+
+	srv := &plusServer{
+		Server: addr,
+		Drain:  utils.Drain(cInfo.Config),
+	}
+
+	for _, proxyAddr := range proxyAddrs {
+		if err := plusAPI(proxyAddr, "POST", upstreamServersPath(name), srv, nil); err != nil {
+			return false, err
+		}
+	}
+
+	servers[addr] = cInfo.ID
+	delete(p.upstreams.removed, cInfo.ID)
+	p.labels.SetContainer(addr, strings.TrimPrefix(cInfo.Name, "/"))
+
+	log().WithFields(logrus.Fields{
+		"container_id": cInfo.ID,
+		"container":    strings.TrimPrefix(cInfo.Name, "/"),
+		"upstream":     name,
+		"server":       addr,
+	}).Info("added server")
+
+	return true, nil
+}
+
+// RemoveServer removes the container servers from the upstreams on the
+// proxies with the nginx plus api at proxyAddrs.  It returns false if the
+// container is unknown and a reload is required.
+func (p *NginxLoadBalancer) RemoveServer(proxyAddrs []string, id string) (bool, error) {
+	if !p.upstreamAPIEnabled() {
+		return false, nil
+	}
+
+	p.upstreams.lock.Lock()
+	defer p.upstreams.lock.Unlock()
+
+	if p.upstreams.removed[id] {
+		return true, nil
+	}
+
+	// upstream -> server addr
+	servers := map[string]string{}
+	for name, upstream := range p.upstreams.upstreams {
+		for addr, cID := range upstream {
+			if cID != "" && cID == id {
+				servers[name] = addr
+			}
+		}
+	}
+
+	if len(servers) == 0 {
+		return false, nil
+	}
+
+	for _, proxyAddr := range proxyAddrs {
+		for name, addr := range servers {
+			var current []*plusServer
+			if err := plusAPI(proxyAddr, "GET", upstreamServersPath(name), nil, &current); err != nil {
+				return false, err
+			}
+
+			for _, srv := range current {
+				if srv.Server != addr {
+					continue
+				}
+
+				path := fmt.Sprintf("%s/%d", upstreamServersPath(name), srv.ID)
+				if err := plusAPI(proxyAddr, "DELETE", path, nil, nil); err != nil {
+					return false, err
+				}
+			}
+		}
+	}
+
+	for name, addr := range servers {
+		delete(p.upstreams.upstreams[name], addr)
+		log().WithFields(logrus.Fields{"container_id": id, "upstream": name, "server": addr}).Info("removed server")
+	}
+	p.upstreams.removed[id] = true
+
+	return true, nil
+}
+
+func upstreamServersPath(name string) string {
+	return "/http/upstreams/" + url.PathEscape(name) + "/servers"
+}
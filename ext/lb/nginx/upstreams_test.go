@@ -0,0 +1,151 @@
+package nginx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext"
+	"github.com/ehazlett/interlock/ext/lb/stats"
+)
+
+// upstreamAPIStub serves the nginx plus upstream servers api and records
+// the requests
+func upstreamAPIStub(requests *[]string) *httptest.Server {
+	servers := []*plusServer{
+		{ID: 0, Server: "10.0.0.2:32768"},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/6/http/upstreams/app.example.com/servers") {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		*requests = append(*requests, r.Method+" "+r.URL.Path)
+
+		switch r.Method {
+		case "GET":
+			json.NewEncoder(w).Encode(servers)
+		case "POST":
+			srv := &plusServer{}
+			if err := json.NewDecoder(r.Body).Decode(srv); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			srv.ID = len(servers)
+			servers = append(servers, srv)
+			w.WriteHeader(http.StatusCreated)
+		case "DELETE":
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+}
+
+func testUpstreamLoadBalancer() *NginxLoadBalancer {
+	p := &NginxLoadBalancer{
+		cfg: &config.ExtensionConfig{
+			NginxPlusEnabled: true,
+			RuntimeAPIPort:   8081,
+		},
+		upstreams: newUpstreamState(),
+		labels:    stats.NewLabels(),
+	}
+
+	p.setUpstreams([]*Host{
+		{
+			ContextRoot: &ContextRoot{},
+			Upstream: &Upstream{
+				Name: "app.example.com",
+				Servers: []*Server{
+					{ID: "abc", Addr: "10.0.0.2:32768"},
+				},
+			},
+		},
+	}, nil)
+
+	return p
+}
+
+func TestAddRemoveServer(t *testing.T) {
+	requests := []string{}
+	s := upstreamAPIStub(&requests)
+	defer s.Close()
+
+	p := testUpstreamLoadBalancer()
+	proxyAddrs := []string{strings.TrimPrefix(s.URL, "http://")}
+
+	cInfo := testContainer()
+	cInfo.ID = "def"
+
+	ok, err := p.AddServer(proxyAddrs, cInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Fatal("expected server to be added without a reload")
+	}
+
+	ok, err = p.RemoveServer(proxyAddrs, cInfo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Fatal("expected server to be removed without a reload")
+	}
+
+	expected := []string{
+		"POST /api/6/http/upstreams/app.example.com/servers",
+		"GET /api/6/http/upstreams/app.example.com/servers",
+		"DELETE /api/6/http/upstreams/app.example.com/servers/1",
+	}
+
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v; received %v", expected, requests)
+	}
+
+	// removed containers are not removed again on destroy
+	ok, err = p.RemoveServer(proxyAddrs, cInfo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok || len(requests) != len(expected) {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+}
+
+func TestAddServerNewUpstream(t *testing.T) {
+	p := testUpstreamLoadBalancer()
+
+	cInfo := testContainer()
+	cInfo.Config.Labels[ext.InterlockDomainLabel] = "other.com"
+
+	ok, err := p.AddServer([]string{"127.0.0.1:0"}, cInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok {
+		t.Fatal("expected a reload for a new upstream")
+	}
+}
+
+func TestAddServerPlusDisabled(t *testing.T) {
+	p := testUpstreamLoadBalancer()
+	p.cfg.NginxPlusEnabled = false
+
+	ok, err := p.AddServer([]string{"127.0.0.1:0"}, testContainer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok {
+		t.Fatal("expected a reload when nginx plus is disabled")
+	}
+}
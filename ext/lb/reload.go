@@ -0,0 +1,391 @@
+package lb
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/engine-api/types"
+	ntypes "github.com/docker/engine-api/types/network"
+	"github.com/ehazlett/interlock/ext"
+	"github.com/ehazlett/interlock/ext/lb/haproxy"
+	"github.com/ehazlett/interlock/ext/lb/nginx"
+	lbutils "github.com/ehazlett/interlock/ext/lb/utils"
+	"github.com/ehazlett/interlock/tracing"
+	"golang.org/x/net/context"
+)
+
+const (
+	// number of reload records kept for the management api
+	reloadHistory = 20
+
+	reloadResultOK    = "ok"
+	reloadResultError = "error"
+)
+
+// triggerReload schedules a reload of the proxies and records the reason
+// for the reload record.  The reload is traced as a child of the span in
+// the context of the first trigger.
+func (l *LoadBalancer) triggerReload(ctx context.Context, trigger string) {
+	l.lock.Lock()
+	if !contains(l.triggers, trigger) {
+		l.triggers = append(l.triggers, trigger)
+	}
+	if l.triggerCtx == nil {
+		l.triggerCtx = ctx
+		l.triggered = time.Now()
+	}
+	l.lock.Unlock()
+
+	log().Debugf("triggering reload: trigger=%s", trigger)
+	l.cache.Set("reload", true)
+}
+
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reloads returns the most recent reload records
+func (l *LoadBalancer) Reloads() []*ext.Reload {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	reloads := make([]*ext.Reload, len(l.reloads))
+	copy(reloads, l.reloads)
+
+	return reloads
+}
+
+// SetReloadHandler sets the func called with the record of each reload
+func (l *LoadBalancer) SetReloadHandler(fn func(r *ext.Reload)) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.reloadHandler = fn
+}
+
+// reload generates the proxy config, copies it to the proxy containers
+// and reloads them
+func (l *LoadBalancer) reload() *ext.Reload {
+	l.lock.Lock()
+	triggers := l.triggers
+	ctx := l.triggerCtx
+	triggered := l.triggered
+	l.triggers = nil
+	l.triggerCtx = nil
+	l.lock.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+		triggered = time.Now()
+	}
+
+	if len(triggers) == 0 {
+		triggers = []string{"unknown"}
+	}
+
+	r := &ext.Reload{
+		ID:      newReloadID(),
+		Ext:     l.backend.Name(),
+		Trigger: strings.Join(triggers, ","),
+		Started: time.Now(),
+		Phases:  map[string]time.Duration{},
+		Result:  reloadResultOK,
+	}
+
+	// the reload id is added to the logs of the reload
+	l.reloadID = r.ID
+	l.backend.SetReloadID(r.ID)
+	defer func() {
+		l.reloadID = ""
+		l.backend.SetReloadID("")
+	}()
+
+	// time from the first trigger to the reload (reload threshold)
+	_, debounce := tracing.StartSpanAt(ctx, "debounce", triggered)
+	debounce.Finish()
+
+	ctx, span := tracing.StartSpan(ctx, "reload")
+	span.SetAttribute("ext", r.Ext)
+	span.SetAttribute("reload_id", r.ID)
+	span.SetAttribute("trigger", r.Trigger)
+
+	if err := l.runReload(ctx, r); err != nil {
+		r.Result = reloadResultError
+		r.Error = err.Error()
+		span.SetError(err)
+		errChan <- err
+	}
+	span.Finish()
+
+	r.Duration = time.Since(r.Started)
+	l.recordReload(r)
+
+	return r
+}
+
+// newReloadID returns a random id for the reload
+func newReloadID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// reloadLog returns the logger with the id of the reload in progress
+func (l *LoadBalancer) reloadLog() *logrus.Entry {
+	if l.reloadID == "" {
+		return log()
+	}
+
+	return log().WithField("reload_id", l.reloadID)
+}
+
+// phase runs fn and records the duration of the reload phase
+func (l *LoadBalancer) phase(ctx context.Context, r *ext.Reload, name string, fn func(ctx context.Context) error) error {
+	ctx, span := tracing.StartSpan(ctx, name)
+	start := time.Now()
+	err := fn(ctx)
+	d := time.Since(start)
+	span.SetError(err)
+	span.Finish()
+
+	r.Phases[name] = d
+	reloadPhaseDuration.WithLabelValues(l.backend.Name(), name).Observe(d.Seconds())
+
+	return err
+}
+
+func (l *LoadBalancer) runReload(ctx context.Context, r *ext.Reload) error {
+	l.reloadLog().Debug("updating load balancers")
+
+	opts := types.ContainerListOptions{
+		All: true,
+	}
+	_, span := tracing.StartSpan(ctx, "list_containers")
+	containers, err := l.client.ContainerList(context.Background(), opts)
+	span.SetError(err)
+	span.Finish()
+	if err != nil {
+		return err
+	}
+
+	// generate proxy config
+	l.reloadLog().Debug("generating proxy config")
+	var cfg interface{}
+	if err := l.phase(ctx, r, "generate", func(ctx context.Context) error {
+		c, err := l.backend.GenerateProxyConfig(containers)
+		cfg = c
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// check ssl certs
+	proxyCerts := l.proxyCerts(cfg)
+	l.lock.Lock()
+	l.certs = proxyCerts
+	l.lock.Unlock()
+	l.checkCerts()
+
+	// diff against the previous config
+	state, err := l.proxyState(cfg, proxyCerts)
+	if err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	diff := diffState(l.state, state)
+	r.Summary = diffSummary(state, diff)
+	// everything is new on the first reload
+	if l.state != nil {
+		r.Diff = diff
+	}
+	l.lock.Unlock()
+
+	// save proxy config
+	l.reloadLog().Debugf("proxy config path: %s", l.backend.ConfigPath())
+
+	proxyContainers, err := l.ProxyContainers(l.backend.Name())
+	if err != nil {
+		return err
+	}
+
+	l.reloadLog().Debugf("proxyContainers: %v", proxyContainers)
+
+	// save config
+	l.reloadLog().Debug("saving proxy config")
+	var rendered *renderedConfig
+	if err := l.phase(ctx, r, "save", func(ctx context.Context) error {
+		c, err := l.renderConfig(cfg)
+		rendered = c
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if err := l.phase(ctx, r, "copy", func(ctx context.Context) error {
+		return l.copyConfig(ctx, rendered, proxyContainers)
+	}); err != nil {
+		rErr, ok := err.(*lbutils.ReloadError)
+		if !ok {
+			return err
+		}
+
+		// the proxies with the config are still reloaded
+		l.proxyFailed(r, "copy", rErr)
+	}
+
+	// connect to networks
+	proxyNetworks := map[string]string{}
+	switch l.backend.Name() {
+	case "nginx":
+		proxyConfig := cfg.(*nginx.Config)
+		proxyNetworks = proxyConfig.Networks
+	case "haproxy":
+		proxyConfig := cfg.(*haproxy.Config)
+		proxyNetworks = proxyConfig.Networks
+	default:
+		return fmt.Errorf("unable to connect to networks; unknown backend: %s", l.backend.Name())
+	}
+
+	for _, cnt := range proxyContainers {
+		for net, _ := range proxyNetworks {
+			if _, ok := cnt.NetworkSettings.Networks[net]; !ok {
+				l.reloadLog().WithFields(logrus.Fields{"container_id": cnt.ID, "network": net}).Debug("connecting proxy container to network")
+
+				// connect
+				_, span := tracing.StartSpan(ctx, "network_connect")
+				span.SetAttribute("container_id", cnt.ID)
+				span.SetAttribute("network", net)
+				err := l.client.NetworkConnect(context.Background(), net, cnt.ID, &ntypes.EndpointSettings{})
+				span.SetError(err)
+				span.Finish()
+				if err != nil {
+					l.reloadLog().WithFields(logrus.Fields{"container_id": cnt.ID, "network": net}).Warnf("unable to connect proxy container to network: err=%s", err)
+					networkConnectFailures.WithLabelValues(l.backend.Name(), net).Inc()
+					continue
+				}
+			}
+		}
+	}
+
+	// get interlock nodes
+	interlockNodes := []types.Container{}
+
+	for _, cnt := range containers {
+		// always include self container
+		if cnt.ID == l.nodeID && cnt.State == "running" {
+			interlockNodes = append(interlockNodes, cnt)
+			continue
+		}
+
+		cInfo, err := l.client.ContainerInspect(context.Background(), cnt.ID)
+		if err != nil {
+			l.reloadLog().Errorf("unable to inspect interlock container: %s", err)
+			continue
+		}
+
+		if strings.Index(cInfo.Config.Image, "interlock") > 0 {
+			if _, ok := cInfo.Config.Labels[ext.InterlockAppLabel]; ok {
+				interlockNodes = append(interlockNodes, cnt)
+			}
+		}
+	}
+
+	proxyContainersToRestart := l.proxyContainersToRestart(interlockNodes, proxyContainers)
+
+	// trigger reload
+	l.reloadLog().Debug("signaling reload")
+
+	// pause to ensure file write sync
+	_, span = tracing.StartSpan(ctx, "file_sync")
+	time.Sleep(time.Millisecond * 1000)
+	span.Finish()
+
+	if err := l.phase(ctx, r, "reload", func(ctx context.Context) error {
+		err := l.backend.Reload(proxyContainersToRestart)
+		if rErr, ok := err.(*lbutils.ReloadError); ok {
+			l.proxyFailed(r, "reload", rErr)
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// the next reload is diffed against the config the proxies run; the
+	// state is kept if any proxy failed so the changes are reported again
+	if len(r.FailedProxies) == 0 {
+		l.lock.Lock()
+		l.state = state
+		l.lock.Unlock()
+	}
+
+	return nil
+}
+
+// proxyFailed records the proxy containers that could not be updated in
+// the reload phase
+func (l *LoadBalancer) proxyFailed(r *ext.Reload, phase string, rErr *lbutils.ReloadError) {
+	if r.FailedProxies == nil {
+		r.FailedProxies = map[string]string{}
+	}
+
+	for id, err := range rErr.Failed {
+		proxyFailures.WithLabelValues(l.backend.Name(), id, phase).Inc()
+		r.FailedProxies[id] = fmt.Sprintf("%s: %s", phase, err)
+	}
+}
+
+// recordReload keeps the reload record for the management api, logs it
+// and calls the reload handler
+func (l *LoadBalancer) recordReload(r *ext.Reload) {
+	l.lock.Lock()
+	l.reloads = append(l.reloads, r)
+	if len(l.reloads) > reloadHistory {
+		l.reloads = l.reloads[len(l.reloads)-reloadHistory:]
+	}
+	handler := l.reloadHandler
+	l.lock.Unlock()
+
+	phases := []string{}
+	for name, d := range r.Phases {
+		phases = append(phases, fmt.Sprintf("%s=%0.2fms", name, d.Seconds()*1000))
+	}
+	sort.Strings(phases)
+
+	entry := l.reloadLog().WithFields(logrus.Fields{
+		"trigger": r.Trigger,
+		"result":  r.Result,
+		"summary": r.Summary,
+		"phases":  strings.Join(phases, ","),
+	})
+
+	if r.Error != "" {
+		entry.WithField("error", r.Error).Warnf("reload failed: duration=%0.2fms", r.Duration.Seconds()*1000)
+	} else {
+		entry.Infof("reload duration: %0.2fms", r.Duration.Seconds()*1000)
+	}
+
+	logDiff(r)
+
+	if l.cfg.DiffWebhook != "" {
+		go postDiff(l.cfg.DiffWebhook, r)
+	}
+
+	if handler != nil {
+		handler(r)
+	}
+}
@@ -0,0 +1,171 @@
+package lb
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/docker/engine-api/types"
+	"github.com/ehazlett/interlock/tracing"
+	"golang.org/x/net/context"
+)
+
+// RuntimeUpdater is implemented by backends that can add and remove
+// upstreams through the proxy runtime api without a reload.  The methods
+// return false if a reload is required to apply the change.
+type RuntimeUpdater interface {
+	AddServer(proxyAddrs []string, cInfo types.ContainerJSON) (bool, error)
+	RemoveServer(proxyAddrs []string, id string) (bool, error)
+}
+
+// proxyAddr returns the address of the port on the proxy container
+func proxyAddr(cnt types.Container, port int) (string, error) {
+	if cnt.NetworkSettings != nil {
+		for _, n := range cnt.NetworkSettings.Networks {
+			if n != nil && n.IPAddress != "" {
+				return fmt.Sprintf("%s:%d", n.IPAddress, port), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("unable to detect address for proxy container %s", cnt.ID)
+}
+
+// nodeNetworks returns the addresses of the interlock container keyed by
+// network
+func (l *LoadBalancer) nodeNetworks() (map[string]string, error) {
+	cInfo, err := l.client.ContainerInspect(context.Background(), l.nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to inspect interlock container: %s", err)
+	}
+
+	networks := map[string]string{}
+	if cInfo.NetworkSettings != nil {
+		for name, n := range cInfo.NetworkSettings.Networks {
+			if n != nil && n.IPAddress != "" {
+				networks[name] = n.IPAddress
+			}
+		}
+	}
+
+	return networks, nil
+}
+
+// setRuntimeAPIClients restricts the proxy runtime api to the addresses of
+// the interlock container
+func (l *LoadBalancer) setRuntimeAPIClients() error {
+	networks, err := l.nodeNetworks()
+	if err != nil {
+		return err
+	}
+
+	if len(networks) == 0 {
+		return fmt.Errorf("the runtime api requires interlock to be connected to a network shared with the proxy containers")
+	}
+
+	addrs := []string{}
+	for _, addr := range networks {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	l.networks = networks
+	l.cfg.RuntimeAPIClients = addrs
+
+	return nil
+}
+
+// proxyRuntimeAddr returns the runtime api address of the proxy container
+// on a network shared with interlock
+func (l *LoadBalancer) proxyRuntimeAddr(cnt types.Container) (string, error) {
+	if cnt.NetworkSettings != nil {
+		names := []string{}
+		for name := range cnt.NetworkSettings.Networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			n := cnt.NetworkSettings.Networks[name]
+			if _, ok := l.networks[name]; ok && n != nil && n.IPAddress != "" {
+				return fmt.Sprintf("%s:%d", n.IPAddress, l.cfg.RuntimeAPIPort), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("proxy container %s is not connected to a network shared with interlock", cnt.ID)
+}
+
+// proxyRuntimeAddrs returns the runtime api addresses of the running
+// proxy containers
+func (l *LoadBalancer) proxyRuntimeAddrs() ([]string, error) {
+	proxyContainers, err := l.ProxyContainers(l.backend.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := []string{}
+	for _, cnt := range proxyContainers {
+		if cnt.State != "running" {
+			continue
+		}
+
+		addr, err := l.proxyRuntimeAddr(cnt)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs = append(addrs, addr)
+	}
+
+	return addrs, nil
+}
+
+// runtimeUpdate adds (or removes) the container upstream through the proxy
+// runtime api.  It returns false if the proxies must be reloaded instead.
+func (l *LoadBalancer) runtimeUpdate(ctx context.Context, id string, add bool) (updated bool) {
+	updater, ok := l.backend.(RuntimeUpdater)
+	if !ok || l.cfg.RuntimeAPIPort == 0 {
+		return false
+	}
+
+	_, span := tracing.StartSpan(ctx, "runtime_update")
+	span.SetAttribute("container_id", id)
+	span.SetAttribute("add", strconv.FormatBool(add))
+	defer func() {
+		span.SetAttribute("updated", strconv.FormatBool(updated))
+		span.Finish()
+	}()
+
+	addrs, err := l.proxyRuntimeAddrs()
+	if err != nil {
+		log().Warnf("unable to get proxy runtime addresses: %s", err)
+		return false
+	}
+
+	if len(addrs) == 0 {
+		return false
+	}
+
+	if add {
+		cInfo, err := l.client.ContainerInspect(context.Background(), id)
+		if err != nil {
+			log().WithField("container_id", id).Warnf("unable to inspect container: err=%s", err)
+			return false
+		}
+
+		updated, err = updater.AddServer(addrs, cInfo)
+		if err != nil {
+			log().WithField("container_id", id).Warnf("unable to add server through runtime api: err=%s", err)
+			return false
+		}
+	} else {
+		updated, err = updater.RemoveServer(addrs, id)
+		if err != nil {
+			log().WithField("container_id", id).Warnf("unable to remove server through runtime api: err=%s", err)
+			return false
+		}
+	}
+
+	return updated
+}
@@ -0,0 +1,44 @@
+package lb
+
+import (
+	"testing"
+
+	"github.com/docker/engine-api/types"
+	"github.com/docker/engine-api/types/network"
+	"github.com/ehazlett/interlock/config"
+)
+
+func TestProxyRuntimeAddr(t *testing.T) {
+	l := &LoadBalancer{
+		cfg: &config.ExtensionConfig{
+			RuntimeAPIPort: 9999,
+		},
+		networks: map[string]string{
+			"interlock-mgmt": "10.1.0.2",
+		},
+	}
+
+	cnt := types.Container{
+		ID: "proxy",
+		NetworkSettings: &types.SummaryNetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"app":            {IPAddress: "10.0.0.5"},
+				"interlock-mgmt": {IPAddress: "10.1.0.5"},
+			},
+		},
+	}
+
+	addr, err := l.proxyRuntimeAddr(cnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr != "10.1.0.5:9999" {
+		t.Fatalf("expected address on the shared network; received %s", addr)
+	}
+
+	delete(cnt.NetworkSettings.Networks, "interlock-mgmt")
+	if _, err := l.proxyRuntimeAddr(cnt); err == nil {
+		t.Fatal("expected error without a shared network")
+	}
+}
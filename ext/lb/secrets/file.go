@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// fileBackend reads secrets from a local directory.  Each secret is
+// encrypted using AES-256-GCM with the SHA-256 sum of the key file as the
+// key and the nonce prepended to the ciphertext.
+type fileBackend struct {
+	path string
+	key  []byte
+}
+
+func newFileBackend(path string, keyPath string) (*fileBackend, error) {
+	if keyPath == "" {
+		return nil, fmt.Errorf("a key path must be specified for a file secret store")
+	}
+
+	key, err := ReadKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileBackend{
+		path: path,
+		key:  key,
+	}, nil
+}
+
+func (b *fileBackend) Get(key string) ([]byte, error) {
+	// prevent reading outside of the secret directory
+	p := filepath.Join(b.path, filepath.Clean("/"+key))
+
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decrypt(b.key, data)
+}
+
+// ReadKey returns the encryption key derived from the specified key file
+func ReadKey(keyPath string) ([]byte, error) {
+	d, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	k := sha256.Sum256(d)
+	return k[:], nil
+}
+
+// Encrypt encrypts the data for use in a file secret store
+func Encrypt(key []byte, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// Decrypt decrypts data from a file secret store
+func Decrypt(key []byte, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	n := gcm.NonceSize()
+	if len(data) < n {
+		return nil, fmt.Errorf("invalid encrypted secret")
+	}
+
+	return gcm.Open(nil, data[:n], data[n:], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(c)
+}
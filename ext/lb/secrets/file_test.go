@@ -0,0 +1,151 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := make([]byte, 32)
+	testData := "secret data"
+
+	enc, err := Encrypt(key, []byte(testData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(enc) == testData {
+		t.Fatal("expected encrypted data")
+	}
+
+	dec, err := Decrypt(key, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(dec) != testData {
+		t.Fatalf("expected %s; received %s", testData, string(dec))
+	}
+}
+
+func TestFileSecretStoreRotated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "interlock-secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyPath := filepath.Join(dir, "key")
+	if err := ioutil.WriteFile(keyPath, []byte("testkey"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ReadKey(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeSecret := func(data string) {
+		enc, err := Encrypt(key, []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(dir, "example.com"), enc, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeSecret("v1")
+
+	s, err := NewSecretStore("file://"+dir, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := s.Get("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "v1" {
+		t.Fatalf("expected v1; received %s", string(data))
+	}
+
+	if rotated := s.Rotated(); len(rotated) != 0 {
+		t.Fatalf("expected no rotated secrets; received %v", rotated)
+	}
+
+	writeSecret("v2")
+
+	if rotated := s.Rotated(); len(rotated) != 1 {
+		t.Fatalf("expected 1 rotated secret; received %v", rotated)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	name := FileName("/certs/example.com")
+
+	if !strings.HasPrefix(name, "certs_example.com_") || !strings.HasSuffix(name, ".pem") {
+		t.Fatalf("expected certs_example.com_<hash>.pem; received %s", name)
+	}
+
+	if strings.Contains(name, "/") {
+		t.Fatalf("expected file name without separators; received %s", name)
+	}
+}
+
+func TestFileNameCollision(t *testing.T) {
+	if FileName("a/b") == FileName("a_b") {
+		t.Fatalf("expected a/b and a_b to differ; received %s", FileName("a/b"))
+	}
+}
+
+type blockingBackend struct {
+	release chan bool
+}
+
+func (b *blockingBackend) Get(key string) ([]byte, error) {
+	if key == "slow" {
+		<-b.release
+	}
+
+	return []byte(key), nil
+}
+
+func TestRotatedDoesNotBlockGet(t *testing.T) {
+	b := &blockingBackend{release: make(chan bool)}
+	s := &SecretStore{
+		backend:   b,
+		lock:      &sync.Mutex{},
+		checksums: map[string]string{"slow": ""},
+	}
+
+	done := make(chan []string)
+	go func() {
+		done <- s.Rotated()
+	}()
+
+	got := make(chan bool)
+	go func() {
+		s.Get("fast")
+		got <- true
+	}()
+
+	select {
+	case <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Get to not wait on Rotated")
+	}
+
+	close(b.release)
+
+	if rotated := <-done; len(rotated) != 1 || rotated[0] != "slow" {
+		t.Fatalf("expected [slow] to be rotated; received %v", rotated)
+	}
+}
@@ -0,0 +1,171 @@
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/docker/libkv"
+	kvstore "github.com/docker/libkv/store"
+)
+
+const (
+	pluginName = "secrets"
+)
+
+// backend is a source for secret material
+type backend interface {
+	Get(key string) ([]byte, error)
+}
+
+// SecretStore fetches tls material from the configured backend and keeps
+// a checksum of each secret to detect rotation
+type SecretStore struct {
+	backend   backend
+	lock      *sync.Mutex
+	checksums map[string]string
+}
+
+func log() *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{
+		"ext": pluginName,
+	})
+}
+
+// NewSecretStore returns a secret store for the specified address.  Supported
+// addresses are consul://<host>/<prefix>, etcd://<host>/<prefix> and
+// file:///<path> for a local encrypted directory.  The kv backends must be
+// registered with libkv by the caller.
+func NewSecretStore(addr string, keyPath string) (*SecretStore, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var b backend
+
+	switch strings.ToLower(u.Scheme) {
+	case "consul", "etcd":
+		kvType := kvstore.CONSUL
+		if strings.ToLower(u.Scheme) == "etcd" {
+			kvType = kvstore.ETCD
+		}
+
+		kv, err := libkv.NewStore(
+			kvType,
+			[]string{u.Host},
+			&kvstore.Config{
+				ConnectionTimeout: time.Second * 10,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		b = &kvBackend{
+			kv:     kv,
+			prefix: strings.Trim(u.Path, "/"),
+		}
+	case "file":
+		fb, err := newFileBackend(u.Path, keyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		b = fb
+	default:
+		return nil, fmt.Errorf("unsupported secret store: %s", u.Scheme)
+	}
+
+	return &SecretStore{
+		backend:   b,
+		lock:      &sync.Mutex{},
+		checksums: map[string]string{},
+	}, nil
+}
+
+// Get returns the secret for the specified key
+func (s *SecretStore) Get(key string) ([]byte, error) {
+	data, err := s.backend.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("error getting secret %s: %s", key, err)
+	}
+
+	s.lock.Lock()
+	s.checksums[key] = checksum(data)
+	s.lock.Unlock()
+
+	return data, nil
+}
+
+// Rotated checks all previously requested secrets and returns the keys
+// that have changed since they were last fetched
+func (s *SecretStore) Rotated() []string {
+	// the backend is not queried under the lock so a slow store does not
+	// block Get during config generation
+	s.lock.Lock()
+	keys := make([]string, 0, len(s.checksums))
+	for key := range s.checksums {
+		keys = append(keys, key)
+	}
+	s.lock.Unlock()
+
+	sums := map[string]string{}
+	for _, key := range keys {
+		data, err := s.backend.Get(key)
+		if err != nil {
+			log().Warnf("unable to check secret for rotation: key=%s err=%s", key, err)
+			continue
+		}
+
+		sums[key] = checksum(data)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rotated := []string{}
+	for key, c := range sums {
+		if c != s.checksums[key] {
+			log().Infof("secret rotated: key=%s", key)
+			rotated = append(rotated, key)
+			s.checksums[key] = c
+		}
+	}
+
+	return rotated
+}
+
+// FileName returns a file name safe representation of the secret key.  the
+// hash of the key keeps keys that only differ in separators (a/b and a_b)
+// apart
+func FileName(key string) string {
+	name := strings.Replace(strings.Trim(key, "/"), "/", "_", -1)
+	h := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%s_%s.pem", name, hex.EncodeToString(h[:4]))
+}
+
+func checksum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+type kvBackend struct {
+	kv     kvstore.Store
+	prefix string
+}
+
+func (b *kvBackend) Get(key string) ([]byte, error) {
+	pair, err := b.kv.Get(path.Join(b.prefix, key))
+	if err != nil {
+		return nil, err
+	}
+
+	return pair.Value, nil
+}
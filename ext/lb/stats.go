@@ -0,0 +1,56 @@
+package lb
+
+import (
+	"strings"
+
+	"github.com/ehazlett/interlock/ext/lb/stats"
+)
+
+// StatsScraper is implemented by backends that can scrape traffic stats
+// from the proxy containers
+type StatsScraper interface {
+	Stats(proxyAddr string) ([]*stats.Sample, error)
+}
+
+// scrapeStats scrapes the traffic stats from the running proxy containers
+// and updates the metrics
+func (l *LoadBalancer) scrapeStats() {
+	scraper, ok := l.backend.(StatsScraper)
+	if !ok {
+		return
+	}
+
+	proxyContainers, err := l.ProxyContainers(l.backend.Name())
+	if err != nil {
+		log().Warnf("unable to get proxy containers for stats: %s", err)
+		return
+	}
+
+	samples := map[string][]*stats.Sample{}
+	for _, cnt := range proxyContainers {
+		if cnt.State != "running" {
+			continue
+		}
+
+		addr, err := proxyAddr(cnt, l.cfg.Port)
+		if err != nil {
+			log().Warn(err)
+			continue
+		}
+
+		s, err := scraper.Stats(addr)
+		if err != nil {
+			log().WithField("container_id", cnt.ID).Warnf("unable to scrape proxy stats: err=%s", err)
+			continue
+		}
+
+		name := cnt.ID[:12]
+		if len(cnt.Names) > 0 {
+			name = strings.TrimPrefix(cnt.Names[0], "/")
+		}
+
+		samples[name] = s
+	}
+
+	stats.Set(l.backend.Name(), samples)
+}
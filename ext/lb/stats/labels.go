@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"sync"
+)
+
+// Labels maps proxy backends and servers to interlock domains and
+// containers.  Backends update the mapping when the proxy config is
+// generated.
+type Labels struct {
+	lock       *sync.Mutex
+	domains    map[string]string // backend -> domain
+	containers map[string]string // server -> container
+}
+
+func NewLabels() *Labels {
+	return &Labels{
+		lock:       &sync.Mutex{},
+		domains:    map[string]string{},
+		containers: map[string]string{},
+	}
+}
+
+// Reset replaces the mapping
+func (l *Labels) Reset(domains, containers map[string]string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.domains = domains
+	l.containers = containers
+}
+
+// SetContainer maps the server to the container
+func (l *Labels) SetContainer(server, container string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.containers[server] = container
+}
+
+// Domain returns the domain for the backend
+func (l *Labels) Domain(backend string) string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.domains[backend]
+}
+
+// Container returns the container for the server
+func (l *Labels) Container(server string) string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.containers[server]
+}
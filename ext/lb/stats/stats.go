@@ -0,0 +1,116 @@
+package stats
+
+import (
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	pluginName = "stats"
+
+	// sample types
+	TypeProxy    = "proxy"
+	TypeFrontend = "frontend"
+	TypeBackend  = "backend"
+	TypeServer   = "server"
+)
+
+func log() *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{
+		"ext": pluginName,
+	})
+}
+
+var (
+	sampleLabels = []string{
+		"ext",
+		"proxy",
+		"type",
+		"name",
+		"domain",
+		"container",
+	}
+
+	requestsDesc = prometheus.NewDesc(
+		"interlock_proxy_requests_total",
+		"Requests handled by the proxy",
+		sampleLabels, nil,
+	)
+	responses5xxDesc = prometheus.NewDesc(
+		"interlock_proxy_responses_5xx_total",
+		"5xx responses returned by the proxy",
+		sampleLabels, nil,
+	)
+	queueDesc = prometheus.NewDesc(
+		"interlock_proxy_queue_current",
+		"Requests queued by the proxy",
+		sampleLabels, nil,
+	)
+	activeDesc = prometheus.NewDesc(
+		"interlock_proxy_connections_active",
+		"Active connections on the proxy",
+		sampleLabels, nil,
+	)
+
+	collector = &proxyCollector{
+		lock:    &sync.Mutex{},
+		samples: map[string][]*Sample{},
+	}
+)
+
+func init() {
+	prometheus.MustRegister(collector)
+}
+
+// Sample is the traffic stats for a proxy frontend, backend or server
+type Sample struct {
+	Type         string
+	Name         string // frontend, backend or upstream name
+	Domain       string
+	Container    string // upstream container for servers
+	Requests     float64
+	Responses5xx float64
+	Queue        float64
+	Active       float64
+}
+
+// Set replaces the samples for the extension keyed by proxy container
+func Set(ext string, samples map[string][]*Sample) {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+
+	collector.ext = ext
+	collector.samples = samples
+}
+
+// proxyCollector exports the last scraped samples.  The proxies report
+// totals so requests and responses are exported as counters.
+type proxyCollector struct {
+	lock    *sync.Mutex
+	ext     string
+	samples map[string][]*Sample
+}
+
+func (c *proxyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- requestsDesc
+	ch <- responses5xxDesc
+	ch <- queueDesc
+	ch <- activeDesc
+}
+
+func (c *proxyCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for proxy, samples := range c.samples {
+		for _, s := range samples {
+			labels := []string{c.ext, proxy, s.Type, s.Name, s.Domain, s.Container}
+			ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, s.Requests, labels...)
+			ch <- prometheus.MustNewConstMetric(responses5xxDesc, prometheus.CounterValue, s.Responses5xx, labels...)
+			ch <- prometheus.MustNewConstMetric(queueDesc, prometheus.GaugeValue, s.Queue, labels...)
+			ch <- prometheus.MustNewConstMetric(activeDesc, prometheus.GaugeValue, s.Active, labels...)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+const (
+	basicAuthSecretPrefix = "secret:"
+)
+
+type AuthUser struct {
+	Name     string
+	Password string
+}
+
+func AllowList(config *ctypes.Config) ([]string, error) {
+	return addressList(config, ext.InterlockAllowLabel)
+}
+
+func DenyList(config *ctypes.Config) ([]string, error) {
+	return addressList(config, ext.InterlockDenyLabel)
+}
+
+// addressList returns the addresses for labels like
+// interlock.allow=10.0.0.0/8,192.168.0.0/16 or interlock.allow.1=10.0.0.0/8
+func addressList(config *ctypes.Config, label string) ([]string, error) {
+	addrs := []string{}
+
+	for l, v := range config.Labels {
+		if l != label && !strings.HasPrefix(l, label+".") {
+			continue
+		}
+
+		for _, a := range strings.Split(v, ",") {
+			a = strings.TrimSpace(a)
+			if a == "" {
+				continue
+			}
+
+			if _, _, err := net.ParseCIDR(a); err != nil {
+				if net.ParseIP(a) == nil {
+					return nil, fmt.Errorf("invalid address %q for %s", a, l)
+				}
+			}
+
+			addrs = append(addrs, a)
+		}
+	}
+
+	sort.Strings(addrs)
+
+	return addrs, nil
+}
+
+// MergeAddressLists returns the sorted addresses of both lists without
+// duplicates.  The access lists of the containers of a domain are merged so
+// a container without labels does not remove the lists of the others.
+func MergeAddressLists(a, b []string) []string {
+	seen := map[string]bool{}
+	addrs := []string{}
+
+	for _, l := range [][]string{a, b} {
+		for _, addr := range l {
+			if seen[addr] {
+				continue
+			}
+
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+
+	sort.Strings(addrs)
+
+	return addrs
+}
+
+// BasicAuth returns the htpasswd source for basic auth.  If the label
+// is in the form interlock.basic_auth=secret:<key> the key is returned
+// and the htpasswd should be loaded from the secret store; otherwise the
+// value is a path to an htpasswd file.
+func BasicAuth(config *ctypes.Config) (string, bool) {
+	if v, ok := config.Labels[ext.InterlockBasicAuthLabel]; ok && v != "" {
+		if strings.HasPrefix(v, basicAuthSecretPrefix) {
+			return v[len(basicAuthSecretPrefix):], true
+		}
+
+		return v, false
+	}
+
+	return "", false
+}
+
+// ParseHtpasswd returns the users from htpasswd data
+func ParseHtpasswd(data []byte) ([]*AuthUser, error) {
+	users := []*AuthUser{}
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(l, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid htpasswd entry: %s", l)
+		}
+
+		users = append(users, &AuthUser{
+			Name:     parts[0],
+			Password: parts[1],
+		})
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
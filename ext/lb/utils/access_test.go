@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"testing"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+func TestAllowList(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockAllowLabel:        "10.0.0.0/8, 192.168.0.0/16",
+			ext.InterlockAllowLabel + ".1": "1.2.3.4",
+		},
+	}
+
+	addrs, err := AllowList(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(addrs) != 3 {
+		t.Fatalf("expected 3 addresses; received %v", addrs)
+	}
+}
+
+func TestDenyListInvalid(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockDenyLabel: "10.0.0.0/33",
+		},
+	}
+
+	if _, err := DenyList(cfg); err == nil {
+		t.Fatal("expected error for invalid address")
+	}
+}
+
+func TestDenyListNoLabels(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{},
+	}
+
+	addrs, err := DenyList(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(addrs) != 0 {
+		t.Fatalf("expected no addresses; received %v", addrs)
+	}
+}
+
+func TestMergeAddressLists(t *testing.T) {
+	// two containers of the same domain; only the first sets the labels
+	first := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockAllowLabel: "10.0.0.0/8,192.168.1.1",
+		},
+	}
+	second := &ctypes.Config{
+		Labels: map[string]string{},
+	}
+
+	allow := []string{}
+	for _, cfg := range []*ctypes.Config{first, second} {
+		addrs, err := AllowList(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		allow = MergeAddressLists(allow, addrs)
+	}
+
+	if len(allow) != 2 || allow[0] != "10.0.0.0/8" || allow[1] != "192.168.1.1" {
+		t.Fatalf("expected allow list of the first container; received %v", allow)
+	}
+
+	merged := MergeAddressLists(allow, []string{"172.16.0.0/12", "10.0.0.0/8"})
+	if len(merged) != 3 || merged[0] != "10.0.0.0/8" || merged[1] != "172.16.0.0/12" {
+		t.Fatalf("unexpected merged list: %v", merged)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockBasicAuthLabel: "/etc/interlock/htpasswd",
+		},
+	}
+
+	src, secret := BasicAuth(cfg)
+	if src != "/etc/interlock/htpasswd" || secret {
+		t.Fatalf("expected htpasswd file; received %s (secret=%v)", src, secret)
+	}
+}
+
+func TestBasicAuthSecret(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockBasicAuthLabel: "secret:auth/admin",
+		},
+	}
+
+	src, secret := BasicAuth(cfg)
+	if src != "auth/admin" || !secret {
+		t.Fatalf("expected htpasswd secret; received %s (secret=%v)", src, secret)
+	}
+}
+
+func TestParseHtpasswd(t *testing.T) {
+	data := []byte("# users\nfoo:$6$salt$hash\n\nbar:$2y$05$hash\n")
+
+	users, err := ParseHtpasswd(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users; received %d", len(users))
+	}
+
+	if users[0].Name != "foo" || users[0].Password != "$6$salt$hash" {
+		t.Fatalf("unexpected user: %v", users[0])
+	}
+}
+
+func TestParseHtpasswdInvalid(t *testing.T) {
+	if _, err := ParseHtpasswd([]byte("foo")); err == nil {
+		t.Fatal("expected error for invalid htpasswd")
+	}
+}
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"fmt"
+	"strings"
+
 	ctypes "github.com/docker/engine-api/types/container"
 	"github.com/ehazlett/interlock/ext"
 )
@@ -14,3 +17,21 @@ func Domain(config *ctypes.Config) string {
 
 	return domain
 }
+
+// ServiceDomain returns the name of the host for the container as used
+// by the proxy config: the context root name if a context root is set or
+// the hostname and domain
+func ServiceDomain(config *ctypes.Config) string {
+	if contextRoot := ContextRoot(config); contextRoot != "" {
+		return strings.Replace(contextRoot, "/", "_", -1)
+	}
+
+	hostname := Hostname(config)
+	domain := Domain(config)
+
+	if domain != "" && hostname != domain && hostname != "" {
+		return fmt.Sprintf("%s.%s", hostname, domain)
+	}
+
+	return domain
+}
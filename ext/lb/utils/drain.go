@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"strconv"
+	"sync"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+// Drain returns true for containers with the label interlock.drain=true.
+// Drained upstreams are kept in the config without receiving new requests.
+func Drain(config *ctypes.Config) bool {
+	if v, ok := config.Labels[ext.InterlockDrainLabel]; ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false
+		}
+
+		return b
+	}
+
+	return false
+}
+
+// Drains is the set of containers whose connections are drained on a
+// graceful stop.  Draining containers are kept in the config without
+// receiving new requests and drained containers are left out of the config
+// until they are started again or removed.
+type Drains struct {
+	lock       *sync.Mutex
+	containers map[string]bool // container id -> drain complete
+}
+
+func NewDrains() *Drains {
+	return &Drains{
+		lock:       &sync.Mutex{},
+		containers: map[string]bool{},
+	}
+}
+
+// Start marks the container as draining.  It returns false if the
+// container is already draining or drained.
+func (d *Drains) Start(id string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if _, ok := d.containers[id]; ok {
+		return false
+	}
+
+	d.containers[id] = false
+
+	return true
+}
+
+// Done marks the drain of the container as complete
+func (d *Drains) Done(id string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if _, ok := d.containers[id]; ok {
+		d.containers[id] = true
+	}
+}
+
+// Remove forgets the container
+func (d *Drains) Remove(id string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.containers, id)
+}
+
+// Draining returns true if the container connections are being drained
+func (d *Drains) Draining(id string) bool {
+	if d == nil {
+		return false
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	done, ok := d.containers[id]
+
+	return ok && !done
+}
+
+// Drained returns true if the container connections have been drained
+func (d *Drains) Drained(id string) bool {
+	if d == nil {
+		return false
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.containers[id]
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"testing"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+func TestDrain(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockDrainLabel: "true",
+		},
+	}
+
+	if !Drain(cfg) {
+		t.Fatal("expected drain to be enabled")
+	}
+}
+
+func TestDrainNoLabel(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{},
+	}
+
+	if Drain(cfg) {
+		t.Fatal("expected drain to be disabled")
+	}
+}
+
+func TestDrains(t *testing.T) {
+	d := NewDrains()
+
+	if !d.Start("abc") {
+		t.Fatal("expected drain to start")
+	}
+
+	if d.Start("abc") {
+		t.Fatal("expected drain to be in progress")
+	}
+
+	if !d.Draining("abc") || d.Drained("abc") {
+		t.Fatal("expected container to be draining")
+	}
+
+	d.Done("abc")
+
+	if d.Draining("abc") || !d.Drained("abc") {
+		t.Fatal("expected container to be drained")
+	}
+
+	if d.Start("abc") {
+		t.Fatal("expected drained container to not drain again")
+	}
+
+	d.Remove("abc")
+
+	if d.Draining("abc") || d.Drained("abc") {
+		t.Fatal("expected container to be removed")
+	}
+
+	// done is ignored for containers that are not draining
+	d.Done("def")
+
+	if d.Drained("def") {
+		t.Fatal("expected container to not be drained")
+	}
+}
+
+func TestDrainsNil(t *testing.T) {
+	var d *Drains
+
+	if d.Draining("abc") || d.Drained("abc") {
+		t.Fatal("expected nil drains to be empty")
+	}
+}
+
+func TestServiceDomain(t *testing.T) {
+	cfg := &ctypes.Config{
+		Hostname: "app",
+		Labels: map[string]string{
+			ext.InterlockDomainLabel: "example.com",
+		},
+	}
+
+	if d := ServiceDomain(cfg); d != "app.example.com" {
+		t.Fatalf("expected app.example.com; received %s", d)
+	}
+
+	cfg.Labels[ext.InterlockContextRootLabel] = "/app"
+
+	if d := ServiceDomain(cfg); d != "_app" {
+		t.Fatalf("expected _app; received %s", d)
+	}
+}
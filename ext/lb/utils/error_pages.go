@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+// ErrorPageCodes are the response codes that can have custom error pages
+var ErrorPageCodes = []int{502, 503, 504}
+
+var (
+	// domains are used as directory names for the pages; context root
+	// names start with an underscore
+	errorPageDomainRegex = regexp.MustCompile(`^[A-Za-z0-9_*]([A-Za-z0-9_*.-]*[A-Za-z0-9_])?$`)
+)
+
+// ErrorPage is a custom error page for a domain.  Path is the location
+// of the page in the proxy container and is set by the backend.
+type ErrorPage struct {
+	Code int
+	Path string
+	Data []byte
+}
+
+// ErrorPages loads the custom error pages for the domain from the error
+// pages directory (<dir>/<domain>/<code>.html falling back to
+// <dir>/<code>.html) and from labels like interlock.error_page.503=app/503.html
+// with a path relative to the directory.  Labels take precedence over the
+// directory.
+func ErrorPages(config *ctypes.Config, domain, pagesPath string) ([]*ErrorPage, error) {
+	if !errorPageDomainRegex.MatchString(domain) || strings.Contains(domain, "..") {
+		return nil, fmt.Errorf("invalid domain for error pages: %q", domain)
+	}
+
+	sources := map[int]string{}
+
+	if pagesPath != "" {
+		for _, code := range ErrorPageCodes {
+			for _, p := range []string{
+				filepath.Join(pagesPath, domain, fmt.Sprintf("%d.html", code)),
+				filepath.Join(pagesPath, fmt.Sprintf("%d.html", code)),
+			} {
+				if _, err := os.Stat(p); err == nil {
+					sources[code] = p
+					break
+				}
+			}
+		}
+	}
+
+	if config != nil {
+		prefix := ext.InterlockErrorPageLabel + "."
+		for l, v := range config.Labels {
+			if !strings.HasPrefix(l, prefix) {
+				continue
+			}
+
+			code, err := strconv.Atoi(strings.TrimPrefix(l, prefix))
+			if err != nil || !isErrorPageCode(code) {
+				return nil, fmt.Errorf("unsupported error page code: %s", l)
+			}
+
+			// pages are served publicly; only pages from the
+			// directory are allowed
+			src, err := ConfinedPath(pagesPath, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid error page for %s: %s", l, err)
+			}
+
+			sources[code] = src
+		}
+	}
+
+	pages := []*ErrorPage{}
+	for code, src := range sources {
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+
+		pages = append(pages, &ErrorPage{
+			Code: code,
+			Data: data,
+		})
+	}
+
+	sort.Sort(errorPagesByCode(pages))
+
+	return pages, nil
+}
+
+func isErrorPageCode(code int) bool {
+	for _, c := range ErrorPageCodes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+type errorPagesByCode []*ErrorPage
+
+func (p errorPagesByCode) Len() int           { return len(p) }
+func (p errorPagesByCode) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p errorPagesByCode) Less(i, j int) bool { return p[i].Code < p[j].Code }
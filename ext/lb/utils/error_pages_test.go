@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+func TestErrorPages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "interlock-error-pages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "example.com"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"502.html":             "default 502",
+		"503.html":             "default 503",
+		"example.com/503.html": "example 503",
+		"label.html":           "label 504",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			fmt.Sprintf("%s.504", ext.InterlockErrorPageLabel): "label.html",
+		},
+	}
+
+	pages, err := ErrorPages(cfg, "example.com", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"default 502", "example 503", "label 504"}
+	if len(pages) != len(expected) {
+		t.Fatalf("expected %d pages; received %d", len(expected), len(pages))
+	}
+
+	for i, p := range pages {
+		if string(p.Data) != expected[i] {
+			t.Fatalf("expected %q for %d; received %q", expected[i], p.Code, p.Data)
+		}
+	}
+}
+
+func TestErrorPagesInvalidCode(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			fmt.Sprintf("%s.404", ext.InterlockErrorPageLabel): "/tmp/404.html",
+		},
+	}
+
+	if _, err := ErrorPages(cfg, "example.com", ""); err == nil {
+		t.Fatal("expected error for unsupported code")
+	}
+}
+
+func TestErrorPagesLabelOutsidePagesPath(t *testing.T) {
+	for _, v := range []string{"/etc/interlock/config.toml", "../config.toml", "app/../../secret.key", ""} {
+		cfg := &ctypes.Config{
+			Labels: map[string]string{
+				fmt.Sprintf("%s.503", ext.InterlockErrorPageLabel): v,
+			},
+		}
+
+		if _, err := ErrorPages(cfg, "example.com", "/etc/interlock/pages"); err == nil {
+			t.Fatalf("expected error for page %q", v)
+		}
+	}
+}
+
+func TestErrorPagesLabelWithoutPagesPath(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			fmt.Sprintf("%s.503", ext.InterlockErrorPageLabel): "503.html",
+		},
+	}
+
+	if _, err := ErrorPages(cfg, "example.com", ""); err == nil {
+		t.Fatal("expected error without error pages path")
+	}
+}
+
+func TestErrorPagesInvalidDomain(t *testing.T) {
+	for _, d := range []string{"", "..", "../etc", "a/b", "example.com/..", "a..b"} {
+		if _, err := ErrorPages(nil, d, ""); err == nil {
+			t.Fatalf("expected error for domain %q", d)
+		}
+	}
+
+	for _, d := range []string{"example.com", "_app", "*.example.com"} {
+		if _, err := ErrorPages(nil, d, ""); err != nil {
+			t.Fatalf("expected domain %q to be valid: %s", d, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ProxyFile is an additional file that is copied into the proxy
+// containers along with the generated config.  Name is relative to
+// the proxy config directory (i.e. certs/example.com.pem).
+type ProxyFile struct {
+	Name string
+	Mode int64
+	Data []byte
+}
+
+// ConfinedPath returns the path of the file name from a label in the
+// directory.  Absolute names and names outside of the directory are
+// rejected so labels cannot read arbitrary files on the interlock host.
+func ConfinedPath(dir, name string) (string, error) {
+	if dir == "" {
+		return "", fmt.Errorf("no directory configured for %q", name)
+	}
+
+	if name == "" || filepath.IsAbs(name) {
+		return "", fmt.Errorf("%q must be relative to %s", name, dir)
+	}
+
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if part == ".." {
+			return "", fmt.Errorf("%q must be relative to %s", name, dir)
+		}
+	}
+
+	return filepath.Join(dir, name), nil
+}
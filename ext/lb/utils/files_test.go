@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestConfinedPath(t *testing.T) {
+	p, err := ConfinedPath("/etc/interlock/auth", "app/htpasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p != "/etc/interlock/auth/app/htpasswd" {
+		t.Fatalf("expected /etc/interlock/auth/app/htpasswd; received %s", p)
+	}
+
+	for _, name := range []string{"/etc/interlock/config.toml", "../config.toml", "app/../../key", ""} {
+		if _, err := ConfinedPath("/etc/interlock/auth", name); err == nil {
+			t.Fatalf("expected error for %q", name)
+		}
+	}
+
+	if _, err := ConfinedPath("", "htpasswd"); err == nil {
+		t.Fatal("expected error without directory")
+	}
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+const (
+	DefaultCORSMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	DefaultCORSHeaders = "Authorization, Content-Type, Accept, Origin, X-Requested-With"
+)
+
+var (
+	// header names are rfc 7230 tokens
+	headerNameRegex = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+)
+
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Quoted returns the header value as a double quoted string
+func (h *Header) Quoted() string {
+	v := strings.Replace(h.Value, `\`, `\\`, -1)
+	v = strings.Replace(v, `"`, `\"`, -1)
+	return fmt.Sprintf(`"%s"`, v)
+}
+
+func RequestHeaders(config *ctypes.Config) ([]*Header, error) {
+	return headers(config, ext.InterlockRequestHeaderLabel)
+}
+
+// ResponseHeaders returns the custom response headers as well as the
+// headers for the cors preset if enabled
+func ResponseHeaders(config *ctypes.Config) ([]*Header, error) {
+	hdrs, err := headers(config, ext.InterlockResponseHeaderLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	cors := CORSHeaders(config)
+	for _, h := range cors {
+		if err := h.validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s label: %s", ext.InterlockCORSLabel, err)
+		}
+	}
+
+	return append(hdrs, cors...), nil
+}
+
+// NginxHeaders returns an error if the header values contain characters
+// that end an nginx directive or block
+func NginxHeaders(hdrs []*Header) error {
+	for _, h := range hdrs {
+		if strings.ContainsAny(h.Value, ";{}") {
+			return fmt.Errorf("invalid value for header %s; ';', '{' and '}' are not allowed", h.Name)
+		}
+	}
+
+	return nil
+}
+
+// validate returns an error if the name is not a valid header name or the
+// value contains line breaks
+func (h *Header) validate() error {
+	if !headerNameRegex.MatchString(h.Name) {
+		return fmt.Errorf("invalid header name %q", h.Name)
+	}
+
+	if strings.ContainsAny(h.Value, "\r\n") {
+		return fmt.Errorf("invalid value for header %s; line breaks are not allowed", h.Name)
+	}
+
+	return nil
+}
+
+// CORSHeaders returns the response headers for labels like
+// interlock.cors=https://example.com
+func CORSHeaders(config *ctypes.Config) []*Header {
+	origin, ok := config.Labels[ext.InterlockCORSLabel]
+	if !ok || origin == "" {
+		return nil
+	}
+
+	hdrs := []*Header{
+		{Name: "Access-Control-Allow-Origin", Value: origin},
+		{Name: "Access-Control-Allow-Methods", Value: DefaultCORSMethods},
+		{Name: "Access-Control-Allow-Headers", Value: DefaultCORSHeaders},
+	}
+
+	// credentials are not allowed with a wildcard origin
+	if origin != "*" {
+		hdrs = append(hdrs,
+			&Header{Name: "Access-Control-Allow-Credentials", Value: "true"},
+			&Header{Name: "Vary", Value: "Origin"},
+		)
+	}
+
+	return hdrs
+}
+
+// headers returns the headers for labels like
+// interlock.request_header.1=X-Foo: bar sorted by label
+func headers(config *ctypes.Config, label string) ([]*Header, error) {
+	keys := []string{}
+	for l := range config.Labels {
+		if l == label || strings.HasPrefix(l, label+".") {
+			keys = append(keys, l)
+		}
+	}
+
+	sort.Strings(keys)
+
+	hdrs := []*Header{}
+	for _, k := range keys {
+		v := config.Labels[k]
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.ContainsAny(parts[0], " \t") {
+			return nil, fmt.Errorf("invalid header %q for %s; expected format Name: value", v, k)
+		}
+
+		h := &Header{
+			Name:  strings.TrimSpace(parts[0]),
+			Value: strings.TrimSpace(parts[1]),
+		}
+
+		if err := h.validate(); err != nil {
+			return nil, fmt.Errorf("%s for %s", err, k)
+		}
+
+		hdrs = append(hdrs, h)
+	}
+
+	return hdrs, nil
+}
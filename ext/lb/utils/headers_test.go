@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"testing"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+func TestRequestHeaders(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockRequestHeaderLabel + ".1": "X-Bar: baz",
+			ext.InterlockRequestHeaderLabel + ".0": "X-Foo: foo: bar",
+		},
+	}
+
+	hdrs, err := RequestHeaders(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hdrs) != 2 {
+		t.Fatalf("expected 2 headers; received %d", len(hdrs))
+	}
+
+	if hdrs[0].Name != "X-Foo" || hdrs[0].Value != "foo: bar" {
+		t.Fatalf("unexpected header: %s: %s", hdrs[0].Name, hdrs[0].Value)
+	}
+}
+
+func TestRequestHeadersInvalid(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockRequestHeaderLabel: "X-Foo",
+		},
+	}
+
+	if _, err := RequestHeaders(cfg); err == nil {
+		t.Fatal("expected error for invalid header")
+	}
+}
+
+func TestRequestHeadersInvalidName(t *testing.T) {
+	for _, v := range []string{"X-Foo{: bar", "X\"Foo: bar", "X/Foo: bar"} {
+		cfg := &ctypes.Config{
+			Labels: map[string]string{
+				ext.InterlockRequestHeaderLabel: v,
+			},
+		}
+
+		if _, err := RequestHeaders(cfg); err == nil {
+			t.Fatalf("expected error for header %q", v)
+		}
+	}
+}
+
+func TestRequestHeadersLineBreak(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockRequestHeaderLabel: "X-Foo: bar\r\nX-Bar: baz",
+		},
+	}
+
+	if _, err := RequestHeaders(cfg); err == nil {
+		t.Fatal("expected error for header with line break")
+	}
+}
+
+func TestResponseHeadersCORSLineBreak(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockCORSLabel: "https://example.com\nX-Foo: bar",
+		},
+	}
+
+	if _, err := ResponseHeaders(cfg); err == nil {
+		t.Fatal("expected error for cors origin with line break")
+	}
+}
+
+func TestNginxHeaders(t *testing.T) {
+	if err := NginxHeaders([]*Header{{Name: "X-Foo", Value: "foo: bar"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []string{"bar; add_header X-Bar baz", "bar{", "bar}"} {
+		if err := NginxHeaders([]*Header{{Name: "X-Foo", Value: v}}); err == nil {
+			t.Fatalf("expected error for header value %q", v)
+		}
+	}
+}
+
+func TestResponseHeadersCORS(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockResponseHeaderLabel: "X-Frame-Options: DENY",
+			ext.InterlockCORSLabel:           "*",
+		},
+	}
+
+	hdrs, err := ResponseHeaders(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hdrs) != 4 {
+		t.Fatalf("expected 4 headers; received %d", len(hdrs))
+	}
+}
+
+func TestCORSHeadersOrigin(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockCORSLabel: "https://example.com",
+		},
+	}
+
+	hdrs := CORSHeaders(cfg)
+
+	if len(hdrs) != 5 {
+		t.Fatalf("expected 5 headers; received %d", len(hdrs))
+	}
+
+	if hdrs[0].Value != "https://example.com" {
+		t.Fatalf("expected origin https://example.com; received %s", hdrs[0].Value)
+	}
+}
+
+func TestHeaderQuoted(t *testing.T) {
+	h := &Header{
+		Name:  "X-Foo",
+		Value: `foo "bar"`,
+	}
+
+	expected := `"foo \"bar\""`
+	if h.Quoted() != expected {
+		t.Fatalf("expected %s; received %s", expected, h.Quoted())
+	}
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"fmt"
+	"regexp"
 	"strconv"
 
 	ctypes "github.com/docker/engine-api/types/container"
@@ -11,6 +13,13 @@ const (
 	DefaultHealthCheckInterval = 5000
 )
 
+var (
+	// the path is rendered unquoted in the proxy configs; only uri path and
+	// query characters without directive separators, quotes or variables
+	// are allowed
+	healthCheckPathRegex = regexp.MustCompile(`^/[A-Za-z0-9._~%!&()*+,=:@/?-]*$`)
+)
+
 func HealthCheck(config *ctypes.Config) string {
 	if v, ok := config.Labels[ext.InterlockHealthCheckLabel]; ok {
 		return v
@@ -34,3 +43,91 @@ func HealthCheckInterval(config *ctypes.Config) (int, error) {
 
 	return checkInterval, nil
 }
+
+var healthCheckLabels = []string{
+	ext.InterlockHealthCheckIntervalLabel,
+	ext.InterlockHealthCheckPathLabel,
+	ext.InterlockHealthCheckStatusLabel,
+	ext.InterlockHealthCheckRiseLabel,
+	ext.InterlockHealthCheckFallLabel,
+	ext.InterlockHealthCheckTimeoutLabel,
+}
+
+// HealthCheckSpec is a backend neutral health check for the upstreams of
+// a service.  Zero values use the proxy defaults.
+type HealthCheckSpec struct {
+	Path     string // http path; tcp check if empty
+	Status   int    // expected response status
+	Interval int    // milliseconds
+	Rise     int    // consecutive successful checks to mark up
+	Fall     int    // consecutive failed checks to mark down
+	Timeout  int    // milliseconds
+}
+
+// HTTP returns true if the check is an http check
+func (h *HealthCheckSpec) HTTP() bool {
+	return h != nil && h.Path != ""
+}
+
+// FailTimeout returns the interval in seconds (rounded up) for proxies
+// that only support passive checks
+func (h *HealthCheckSpec) FailTimeout() int {
+	return (h.Interval + 999) / 1000
+}
+
+// HealthCheckConfig returns the health check spec for labels like
+// interlock.health_check_path=/health and interlock.health_check_status=200
+// or nil if no health check labels are set
+func HealthCheckConfig(config *ctypes.Config) (*HealthCheckSpec, error) {
+	configured := false
+	for _, l := range healthCheckLabels {
+		if _, ok := config.Labels[l]; ok {
+			configured = true
+			break
+		}
+	}
+
+	if !configured {
+		return nil, nil
+	}
+
+	interval, err := HealthCheckInterval(config)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &HealthCheckSpec{
+		Interval: interval,
+	}
+
+	if v, ok := config.Labels[ext.InterlockHealthCheckPathLabel]; ok && v != "" {
+		if !healthCheckPathRegex.MatchString(v) {
+			return nil, fmt.Errorf("invalid health check path: %s", v)
+		}
+		spec.Path = v
+	}
+
+	for label, dst := range map[string]*int{
+		ext.InterlockHealthCheckStatusLabel:  &spec.Status,
+		ext.InterlockHealthCheckRiseLabel:    &spec.Rise,
+		ext.InterlockHealthCheckFallLabel:    &spec.Fall,
+		ext.InterlockHealthCheckTimeoutLabel: &spec.Timeout,
+	} {
+		v, ok := config.Labels[label]
+		if !ok || v == "" {
+			continue
+		}
+
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("invalid value for %s: %s", label, v)
+		}
+		*dst = i
+	}
+
+	if spec.Status != 0 && (spec.Status < 100 || spec.Status > 599) {
+		return nil, fmt.Errorf("invalid health check status: %d", spec.Status)
+	}
+
+	return spec, nil
+}
@@ -69,3 +69,89 @@ func TestHealthCheckIntervalNoLabel(t *testing.T) {
 		t.Fatalf("expected %s; received %s", DefaultHealthCheckInterval, i)
 	}
 }
+
+func TestHealthCheckConfig(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockHealthCheckPathLabel:    "/health",
+			ext.InterlockHealthCheckStatusLabel:  "200",
+			ext.InterlockHealthCheckRiseLabel:    "2",
+			ext.InterlockHealthCheckFallLabel:    "3",
+			ext.InterlockHealthCheckTimeoutLabel: "1500",
+		},
+	}
+
+	spec, err := HealthCheckConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !spec.HTTP() || spec.Path != "/health" {
+		t.Fatalf("expected http check for /health; received %q", spec.Path)
+	}
+
+	if spec.Status != 200 || spec.Rise != 2 || spec.Fall != 3 || spec.Timeout != 1500 {
+		t.Fatalf("unexpected health check: %+v", spec)
+	}
+
+	if spec.Interval != DefaultHealthCheckInterval {
+		t.Fatalf("expected interval %d; received %d", DefaultHealthCheckInterval, spec.Interval)
+	}
+
+	if spec.FailTimeout() != 5 {
+		t.Fatalf("expected fail timeout 5; received %d", spec.FailTimeout())
+	}
+}
+
+func TestHealthCheckConfigNoLabels(t *testing.T) {
+	spec, err := HealthCheckConfig(&ctypes.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec != nil {
+		t.Fatalf("expected no health check; received %+v", spec)
+	}
+}
+
+func TestHealthCheckConfigInvalidPath(t *testing.T) {
+	for _, v := range []string{"/x;}", "/x\nhealth_check", "/x\r", "/x y", "/x{", "/x\"", "/x'", "/$host", "/x#y"} {
+		cfg := &ctypes.Config{
+			Labels: map[string]string{
+				ext.InterlockHealthCheckPathLabel: v,
+			},
+		}
+
+		if _, err := HealthCheckConfig(cfg); err == nil {
+			t.Fatalf("expected error for path %q", v)
+		}
+	}
+
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockHealthCheckPathLabel: "/health/ready?full=1&x=%20",
+		},
+	}
+
+	if _, err := HealthCheckConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHealthCheckConfigInvalid(t *testing.T) {
+	for l, v := range map[string]string{
+		ext.InterlockHealthCheckPathLabel:   "health",
+		ext.InterlockHealthCheckStatusLabel: "2000",
+		ext.InterlockHealthCheckFallLabel:   "-1",
+	} {
+		cfg := &ctypes.Config{
+			Labels: map[string]string{
+				l: v,
+			},
+		}
+
+		if _, err := HealthCheckConfig(cfg); err == nil {
+			t.Fatalf("expected error for %s=%s", l, v)
+		}
+	}
+}
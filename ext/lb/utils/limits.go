@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+const (
+	limitKeyHeaderPrefix = "header:"
+)
+
+var (
+	rateLimitRegex = regexp.MustCompile(`^(\d+)r/(s|m)$`)
+)
+
+type RateLimit struct {
+	Rate   int
+	Period string // s or m
+}
+
+func (r *RateLimit) String() string {
+	return fmt.Sprintf("%dr/%s", r.Rate, r.Period)
+}
+
+// RateLimitConfig returns the request rate limit for labels like
+// interlock.rate_limit=100r/s
+func RateLimitConfig(config *ctypes.Config) (*RateLimit, error) {
+	v, ok := config.Labels[ext.InterlockRateLimitLabel]
+	if !ok || v == "" {
+		return nil, nil
+	}
+
+	m := rateLimitRegex.FindStringSubmatch(strings.TrimSpace(v))
+	if m == nil {
+		return nil, fmt.Errorf("invalid rate limit %q; expected format like 100r/s or 10r/m", v)
+	}
+
+	rate, err := strconv.Atoi(m[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &RateLimit{
+		Rate:   rate,
+		Period: m[2],
+	}, nil
+}
+
+func ConnLimit(config *ctypes.Config) (int, error) {
+	if v, ok := config.Labels[ext.InterlockConnLimitLabel]; ok && v != "" {
+		return strconv.Atoi(v)
+	}
+
+	return 0, nil
+}
+
+// LimitKeyHeader returns the header to use as the key for rate and
+// connection limits (i.e. interlock.limit_key=header:X-API-Key).  If empty
+// the client ip is used.
+func LimitKeyHeader(config *ctypes.Config) string {
+	if v, ok := config.Labels[ext.InterlockLimitKeyLabel]; ok {
+		if strings.HasPrefix(v, limitKeyHeaderPrefix) {
+			return strings.TrimSpace(v[len(limitKeyHeaderPrefix):])
+		}
+	}
+
+	return ""
+}
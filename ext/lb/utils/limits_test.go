@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"testing"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+func TestRateLimitConfig(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockRateLimitLabel: "100r/s",
+		},
+	}
+
+	r, err := RateLimitConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Rate != 100 || r.Period != "s" {
+		t.Fatalf("expected 100r/s; received %s", r)
+	}
+}
+
+func TestRateLimitConfigInvalid(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockRateLimitLabel: "100/h",
+		},
+	}
+
+	if _, err := RateLimitConfig(cfg); err == nil {
+		t.Fatal("expected error for invalid rate limit")
+	}
+}
+
+func TestRateLimitConfigNoLabel(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{},
+	}
+
+	r, err := RateLimitConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r != nil {
+		t.Fatalf("expected no rate limit; received %s", r)
+	}
+}
+
+func TestConnLimit(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockConnLimitLabel: "50",
+		},
+	}
+
+	l, err := ConnLimit(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l != 50 {
+		t.Fatalf("expected 50; received %d", l)
+	}
+}
+
+func TestLimitKeyHeader(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockLimitKeyLabel: "header:X-API-Key",
+		},
+	}
+
+	if h := LimitKeyHeader(cfg); h != "X-API-Key" {
+		t.Fatalf("expected X-API-Key; received %s", h)
+	}
+}
+
+func TestLimitKeyHeaderDefault(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockLimitKeyLabel: "ip",
+		},
+	}
+
+	if h := LimitKeyHeader(cfg); h != "" {
+		t.Fatalf("expected client ip key; received %s", h)
+	}
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+// Maintenance is the set of domains that have been put into maintenance
+// mode through the management api
+type Maintenance struct {
+	lock    *sync.Mutex
+	domains map[string]bool
+}
+
+func NewMaintenance() *Maintenance {
+	return &Maintenance{
+		lock:    &sync.Mutex{},
+		domains: map[string]bool{},
+	}
+}
+
+// Set enables or disables maintenance mode for the domain.  It returns
+// true if the state changed.
+func (m *Maintenance) Set(domain string, enabled bool) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.domains[domain] == enabled {
+		return false
+	}
+
+	if enabled {
+		m.domains[domain] = true
+	} else {
+		delete(m.domains, domain)
+	}
+
+	return true
+}
+
+// Enabled returns true if the domain is in maintenance mode
+func (m *Maintenance) Enabled(domain string) bool {
+	if m == nil {
+		return false
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.domains[domain]
+}
+
+// Domains returns the sorted domains in maintenance mode
+func (m *Maintenance) Domains() []string {
+	domains := []string{}
+	if m == nil {
+		return domains
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for d := range m.domains {
+		domains = append(domains, d)
+	}
+
+	sort.Strings(domains)
+
+	return domains
+}
+
+// MaintenanceEnabled returns true for containers with the label
+// interlock.maintenance=true
+func MaintenanceEnabled(config *ctypes.Config) bool {
+	if v, ok := config.Labels[ext.InterlockMaintenanceLabel]; ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false
+		}
+
+		return b
+	}
+
+	return false
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"testing"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+func TestMaintenance(t *testing.T) {
+	m := NewMaintenance()
+
+	if !m.Set("b.example.com", true) {
+		t.Fatal("expected state change")
+	}
+
+	if m.Set("b.example.com", true) {
+		t.Fatal("expected no state change")
+	}
+
+	m.Set("a.example.com", true)
+
+	domains := m.Domains()
+	if len(domains) != 2 || domains[0] != "a.example.com" {
+		t.Fatalf("unexpected domains: %v", domains)
+	}
+
+	if !m.Set("b.example.com", false) {
+		t.Fatal("expected state change")
+	}
+
+	if m.Enabled("b.example.com") {
+		t.Fatal("expected maintenance to be disabled")
+	}
+}
+
+func TestMaintenanceEnabled(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockMaintenanceLabel: "true",
+		},
+	}
+
+	if !MaintenanceEnabled(cfg) {
+		t.Fatal("expected maintenance to be enabled")
+	}
+
+	if MaintenanceEnabled(&ctypes.Config{}) {
+		t.Fatal("expected maintenance to be disabled")
+	}
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"fmt"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+const (
+	ProtocolHTTP = "http"
+	ProtocolGRPC = "grpc" // grpc upstream
+	ProtocolH2C  = "h2c"  // cleartext http/2 upstream
+	ProtocolH2   = "h2"   // http/2 over tls upstream
+)
+
+// Protocol returns the upstream protocol for labels like
+// interlock.protocol=grpc
+func Protocol(config *ctypes.Config) (string, error) {
+	v, ok := config.Labels[ext.InterlockProtocolLabel]
+	if !ok || v == "" {
+		return ProtocolHTTP, nil
+	}
+
+	switch v {
+	case ProtocolHTTP, ProtocolGRPC, ProtocolH2C, ProtocolH2:
+		return v, nil
+	}
+
+	return "", fmt.Errorf("unsupported protocol: %s", v)
+}
+
+// HTTP2 returns true if the protocol requires http/2 on the listener
+func HTTP2(protocol string) bool {
+	return protocol == ProtocolGRPC || protocol == ProtocolH2C || protocol == ProtocolH2
+}
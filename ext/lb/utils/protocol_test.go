@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"testing"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+func TestProtocol(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockProtocolLabel: ProtocolGRPC,
+		},
+	}
+
+	p, err := Protocol(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p != ProtocolGRPC {
+		t.Fatalf("expected %s; received %s", ProtocolGRPC, p)
+	}
+
+	if !HTTP2(p) {
+		t.Fatal("expected http/2")
+	}
+}
+
+func TestProtocolDefault(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{},
+	}
+
+	p, err := Protocol(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p != ProtocolHTTP {
+		t.Fatalf("expected %s; received %s", ProtocolHTTP, p)
+	}
+
+	if HTTP2(p) {
+		t.Fatal("expected http/1.1")
+	}
+}
+
+func TestProtocolInvalid(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockProtocolLabel: "spdy",
+		},
+	}
+
+	if _, err := Protocol(cfg); err == nil {
+		t.Fatal("expected error for unsupported protocol")
+	}
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ReloadError is returned when one or more proxy containers could not be
+// updated (config copy or reload).  Failed is keyed by container id.
+type ReloadError struct {
+	Failed map[string]error
+}
+
+func (e *ReloadError) Error() string {
+	ids := []string{}
+	for id := range e.Failed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	errs := []string{}
+	for _, id := range ids {
+		errs = append(errs, fmt.Sprintf("%s: %s", id, e.Failed[id]))
+	}
+
+	return fmt.Sprintf("error updating proxy containers: %s", strings.Join(errs, "; "))
+}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext"
+)
+
+const (
+	RouteTypeRedirect    = "redirect"
+	RouteTypeWWW         = "www"
+	RouteTypeStatic      = "static"
+	DefaultRedirectCode  = 301
+	DefaultStaticCode    = 503
+	DefaultStaticBody    = "Service Unavailable"
+	routeNameReplacement = "_"
+)
+
+// Route is a redirect or static response for a domain that is served
+// by the proxy without an upstream
+type Route struct {
+	Name         string
+	Domain       string
+	Redirect     string
+	PreservePath bool
+	Code         int
+	Body         string
+}
+
+// QuotedBody returns the static response body as a double quoted string
+func (r *Route) QuotedBody() string {
+	h := &Header{Value: r.Body}
+	return h.Quoted()
+}
+
+// StaticRoute returns the route for a marker container with labels like
+// interlock.redirect=https://example.com or interlock.static_response=503
+func StaticRoute(config *ctypes.Config, domain string) (*Route, error) {
+	if target, ok := config.Labels[ext.InterlockRedirectLabel]; ok && target != "" {
+		code := DefaultRedirectCode
+		if v, ok := config.Labels[ext.InterlockRedirectCodeLabel]; ok && v != "" {
+			c, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, err
+			}
+			code = c
+		}
+
+		return newRoute(domain, target, code, "", true)
+	}
+
+	if v, ok := config.Labels[ext.InterlockStaticResponseLabel]; ok {
+		code := DefaultStaticCode
+		if v != "" {
+			c, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, err
+			}
+			code = c
+		}
+
+		body := DefaultStaticBody
+		if b, ok := config.Labels[ext.InterlockStaticBodyLabel]; ok {
+			body = b
+		}
+
+		return newRoute(domain, "", code, body, false)
+	}
+
+	return nil, nil
+}
+
+// ConfigRoutes returns the routes from the extension config
+func ConfigRoutes(routes []*config.Route) ([]*Route, error) {
+	r := []*Route{}
+
+	for _, c := range routes {
+		if c.Domain == "" {
+			return nil, fmt.Errorf("domain must be specified for route")
+		}
+
+		switch c.Type {
+		case RouteTypeRedirect:
+			code := c.Code
+			if code == 0 {
+				code = DefaultRedirectCode
+			}
+
+			route, err := newRoute(c.Domain, c.Target, code, "", c.PreservePath)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, route)
+		case RouteTypeWWW:
+			// redirect www.<domain> to <domain>
+			code := c.Code
+			if code == 0 {
+				code = DefaultRedirectCode
+			}
+
+			target := c.Target
+			if target == "" {
+				target = fmt.Sprintf("http://%s", c.Domain)
+			}
+
+			route, err := newRoute("www."+c.Domain, target, code, "", true)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, route)
+		case RouteTypeStatic:
+			code := c.Code
+			if code == 0 {
+				code = DefaultStaticCode
+			}
+
+			body := c.Body
+			if body == "" {
+				body = DefaultStaticBody
+			}
+
+			route, err := newRoute(c.Domain, "", code, body, false)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, route)
+		default:
+			return nil, fmt.Errorf("unknown route type for %s: %s", c.Domain, c.Type)
+		}
+	}
+
+	return r, nil
+}
+
+func newRoute(domain, target string, code int, body string, preservePath bool) (*Route, error) {
+	if target != "" {
+		switch code {
+		case 301, 302, 303, 307, 308:
+		default:
+			return nil, fmt.Errorf("invalid redirect code for %s: %d", domain, code)
+		}
+
+		// the target is rendered unquoted
+		if strings.IndexFunc(target, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+			return nil, fmt.Errorf("invalid redirect target for %s; whitespace is not allowed", domain)
+		}
+
+		// the request path is appended to the target
+		if preservePath {
+			target = strings.TrimSuffix(target, "/")
+		}
+	} else if code < 200 || code > 599 {
+		return nil, fmt.Errorf("invalid response code for %s: %d", domain, code)
+	}
+
+	if strings.ContainsAny(body, "\r\n") {
+		return nil, fmt.Errorf("invalid response body for %s; line breaks are not allowed", domain)
+	}
+
+	return &Route{
+		Name:         strings.NewReplacer(".", routeNameReplacement, "-", routeNameReplacement).Replace(domain),
+		Domain:       domain,
+		Redirect:     target,
+		PreservePath: preservePath,
+		Code:         code,
+		Body:         body,
+	}, nil
+}
+
+// NginxRoutes returns an error if a redirect target contains characters
+// that end an nginx directive or block
+func NginxRoutes(routes []*Route) error {
+	for _, r := range routes {
+		if strings.ContainsAny(r.Redirect, ";{}") {
+			return fmt.Errorf("invalid redirect target for %s; ';', '{' and '}' are not allowed", r.Domain)
+		}
+	}
+
+	return nil
+}
+
+// FilterRoutes returns the routes excluding duplicate domains and domains
+// that are served by upstream containers
+func FilterRoutes(routes []*Route, upstreamDomains map[string]bool) []*Route {
+	filtered := []*Route{}
+	seen := map[string]bool{}
+
+	for _, r := range routes {
+		if upstreamDomains[r.Domain] || seen[r.Domain] {
+			continue
+		}
+
+		seen[r.Domain] = true
+		filtered = append(filtered, r)
+	}
+
+	return filtered
+}
@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"testing"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext"
+)
+
+func TestStaticRouteRedirect(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockRedirectLabel:     "https://new.example.com/",
+			ext.InterlockRedirectCodeLabel: "302",
+		},
+	}
+
+	r, err := StaticRoute(cfg, "old.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Redirect != "https://new.example.com" || r.Code != 302 || !r.PreservePath {
+		t.Fatalf("unexpected route: %+v", r)
+	}
+
+	if r.Name != "old_example_com" {
+		t.Fatalf("expected old_example_com; received %s", r.Name)
+	}
+}
+
+func TestStaticRouteResponse(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockStaticResponseLabel: "",
+		},
+	}
+
+	r, err := StaticRoute(cfg, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Code != DefaultStaticCode || r.Body != DefaultStaticBody {
+		t.Fatalf("unexpected route: %+v", r)
+	}
+}
+
+func TestStaticRouteNoLabels(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{},
+	}
+
+	r, err := StaticRoute(cfg, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r != nil {
+		t.Fatalf("expected no route; received %+v", r)
+	}
+}
+
+func TestStaticRouteInvalidRedirectCode(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockRedirectLabel:     "https://example.com",
+			ext.InterlockRedirectCodeLabel: "200",
+		},
+	}
+
+	if _, err := StaticRoute(cfg, "example.com"); err == nil {
+		t.Fatal("expected error for invalid redirect code")
+	}
+}
+
+func TestConfigRoutes(t *testing.T) {
+	routes, err := ConfigRoutes([]*config.Route{
+		{Type: RouteTypeWWW, Domain: "example.com", Target: "https://example.com"},
+		{Type: RouteTypeRedirect, Domain: "old.example.com", Target: "https://example.com/new", Code: 307},
+		{Type: RouteTypeStatic, Domain: "down.example.com", Body: "maintenance"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(routes) != 3 {
+		t.Fatalf("expected 3 routes; received %d", len(routes))
+	}
+
+	if routes[0].Domain != "www.example.com" || routes[0].Code != DefaultRedirectCode || !routes[0].PreservePath {
+		t.Fatalf("unexpected www route: %+v", routes[0])
+	}
+
+	if routes[1].PreservePath || routes[1].Code != 307 {
+		t.Fatalf("unexpected redirect route: %+v", routes[1])
+	}
+
+	if routes[2].Code != DefaultStaticCode || routes[2].Body != "maintenance" {
+		t.Fatalf("unexpected static route: %+v", routes[2])
+	}
+}
+
+func TestConfigRoutesUnknownType(t *testing.T) {
+	if _, err := ConfigRoutes([]*config.Route{{Type: "foo", Domain: "example.com"}}); err == nil {
+		t.Fatal("expected error for unknown route type")
+	}
+}
+
+func TestStaticRouteInvalidRedirectTarget(t *testing.T) {
+	for _, target := range []string{"http://x\nlisten evil", "http://x\r\n", "http://x/ a", "http://x\t"} {
+		cfg := &ctypes.Config{
+			Labels: map[string]string{
+				ext.InterlockRedirectLabel: target,
+			},
+		}
+
+		if _, err := StaticRoute(cfg, "example.com"); err == nil {
+			t.Fatalf("expected error for redirect target %q", target)
+		}
+	}
+}
+
+func TestStaticRouteInvalidBody(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockStaticResponseLabel: "503",
+			ext.InterlockStaticBodyLabel:     "down\nhttp-request deny",
+		},
+	}
+
+	if _, err := StaticRoute(cfg, "example.com"); err == nil {
+		t.Fatal("expected error for body with line break")
+	}
+}
+
+func TestNginxRoutes(t *testing.T) {
+	if err := NginxRoutes([]*Route{{Domain: "example.com", Redirect: "https://example.org/a?b=c"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, target := range []string{"http://x;", "http://x}", "http://x{"} {
+		if err := NginxRoutes([]*Route{{Domain: "example.com", Redirect: target}}); err == nil {
+			t.Fatalf("expected error for redirect target %q", target)
+		}
+	}
+}
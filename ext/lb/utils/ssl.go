@@ -49,6 +49,14 @@ func SSLCertKey(config *ctypes.Config) string {
 	return ""
 }
 
+func SSLSecret(config *ctypes.Config) string {
+	if v, ok := config.Labels[ext.InterlockSSLSecretLabel]; ok {
+		return v
+	}
+
+	return ""
+}
+
 func SSLBackendTLSVerify(config *ctypes.Config) string {
 	verify := DefaultSSLBackendTLSVerify
 
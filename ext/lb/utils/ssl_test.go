@@ -120,3 +120,27 @@ func TestSSLCertKeyNoLabel(t *testing.T) {
 		t.Fatal("expected no ssl key")
 	}
 }
+
+func TestSSLSecret(t *testing.T) {
+	testSecret := "certs/example.com"
+
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockSSLSecretLabel: testSecret,
+		},
+	}
+
+	if SSLSecret(cfg) != testSecret {
+		t.Fatalf("expected ssl secret %s", testSecret)
+	}
+}
+
+func TestSSLSecretNoLabel(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{},
+	}
+
+	if SSLSecret(cfg) != "" {
+		t.Fatal("expected no ssl secret")
+	}
+}
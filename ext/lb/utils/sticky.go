@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+const (
+	stickyCookiePrefix = "cookie:"
+)
+
+var (
+	// cookie names are also used in proxy variable names
+	stickyCookieNameRegex  = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	stickyCookieValueRegex = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+)
+
+// StickyCookie returns the cookie name for sticky sessions for labels
+// like interlock.sticky=cookie:SRVID
+func StickyCookie(config *ctypes.Config) (string, error) {
+	v, ok := config.Labels[ext.InterlockStickyLabel]
+	if !ok || v == "" {
+		return "", nil
+	}
+
+	if !strings.HasPrefix(v, stickyCookiePrefix) {
+		return "", fmt.Errorf("unsupported sticky session type: %s", v)
+	}
+
+	name := strings.TrimPrefix(v, stickyCookiePrefix)
+	if !stickyCookieNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid sticky session cookie name: %s", name)
+	}
+
+	return name, nil
+}
+
+// StickyCookieValue returns the cookie value that identifies the server
+// for the container
+func StickyCookieValue(containerName string) string {
+	return stickyCookieValueRegex.ReplaceAllString(containerName, "_")
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"testing"
+
+	ctypes "github.com/docker/engine-api/types/container"
+	"github.com/ehazlett/interlock/ext"
+)
+
+func TestStickyCookie(t *testing.T) {
+	cfg := &ctypes.Config{
+		Labels: map[string]string{
+			ext.InterlockStickyLabel: "cookie:SRVID",
+		},
+	}
+
+	name, err := StickyCookie(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name != "SRVID" {
+		t.Fatalf("expected SRVID; received %s", name)
+	}
+}
+
+func TestStickyCookieNotSet(t *testing.T) {
+	name, err := StickyCookie(&ctypes.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name != "" {
+		t.Fatalf("expected no cookie; received %s", name)
+	}
+}
+
+func TestStickyCookieInvalid(t *testing.T) {
+	for _, v := range []string{"ip", "cookie:", "cookie:SRV-ID"} {
+		cfg := &ctypes.Config{
+			Labels: map[string]string{
+				ext.InterlockStickyLabel: v,
+			},
+		}
+
+		if _, err := StickyCookie(cfg); err == nil {
+			t.Fatalf("expected error for %s", v)
+		}
+	}
+}
+
+func TestStickyCookieValue(t *testing.T) {
+	if v := StickyCookieValue("node-1/app.1"); v != "node-1_app.1" {
+		t.Fatalf("expected node-1_app.1; received %s", v)
+	}
+}
@@ -0,0 +1,113 @@
+package ext
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Reload is the record of a proxy reload
+type Reload struct {
+	ID       string                   `json:"id"`
+	Ext      string                   `json:"ext"`
+	Trigger  string                   `json:"trigger"`
+	Started  time.Time                `json:"started"`
+	Duration time.Duration            `json:"duration"`
+	Phases   map[string]time.Duration `json:"phases"`
+	Summary  string                   `json:"summary"`
+	Diff     *ConfigDiff              `json:"diff,omitempty"`
+	Result   string                   `json:"result"`
+	Error    string                   `json:"error,omitempty"`
+	// proxy containers that could not be updated by container id
+	FailedProxies map[string]string `json:"failed_proxies,omitempty"`
+}
+
+// ReloadExtension is implemented by extensions that reload proxies
+type ReloadExtension interface {
+	Extension
+	// Reloads returns the most recent reload records
+	Reloads() []*Reload
+	// SetReloadHandler sets the func called with the record of each reload
+	SetReloadHandler(fn func(r *Reload))
+}
+
+// TLSChange is a change of the certificate served for a host
+type TLSChange struct {
+	Host   string `json:"host"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// ConfigDiff is the routing change between two proxy configs
+type ConfigDiff struct {
+	HostsAdded       []string            `json:"hosts_added,omitempty"`
+	HostsRemoved     []string            `json:"hosts_removed,omitempty"`
+	UpstreamsAdded   map[string][]string `json:"upstreams_added,omitempty"`
+	UpstreamsRemoved map[string][]string `json:"upstreams_removed,omitempty"`
+	TLSChanged       []*TLSChange        `json:"tls_changed,omitempty"`
+}
+
+// Empty returns true if the configs route the same
+func (d *ConfigDiff) Empty() bool {
+	return len(d.HostsAdded) == 0 && len(d.HostsRemoved) == 0 &&
+		len(d.UpstreamsAdded) == 0 && len(d.UpstreamsRemoved) == 0 &&
+		len(d.TLSChanged) == 0
+}
+
+// Lines returns the changes in human readable form; one per line
+func (d *ConfigDiff) Lines() []string {
+	lines := []string{}
+
+	for _, h := range d.HostsAdded {
+		lines = append(lines, fmt.Sprintf("+ host %s", h))
+	}
+
+	for _, h := range d.HostsRemoved {
+		lines = append(lines, fmt.Sprintf("- host %s", h))
+	}
+
+	for _, h := range sortedKeys(d.UpstreamsAdded) {
+		for _, addr := range d.UpstreamsAdded[h] {
+			lines = append(lines, fmt.Sprintf("+ upstream %s %s", h, addr))
+		}
+	}
+
+	for _, h := range sortedKeys(d.UpstreamsRemoved) {
+		for _, addr := range d.UpstreamsRemoved[h] {
+			lines = append(lines, fmt.Sprintf("- upstream %s %s", h, addr))
+		}
+	}
+
+	for _, c := range d.TLSChanged {
+		lines = append(lines, fmt.Sprintf("~ tls %s: %s -> %s", c.Host, tlsDesc(c.Before), tlsDesc(c.After)))
+	}
+
+	return lines
+}
+
+func (d *ConfigDiff) String() string {
+	if d.Empty() {
+		return "no changes"
+	}
+
+	return strings.Join(d.Lines(), "\n")
+}
+
+func tlsDesc(s string) string {
+	if s == "" {
+		return "none"
+	}
+
+	return s
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
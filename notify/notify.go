@@ -0,0 +1,239 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/ehazlett/interlock/config"
+)
+
+const (
+	EventReload        = "reload"         // proxies reloaded
+	EventReloadFailed  = "reload_failed"  // proxy reload failed
+	EventProxyFailed   = "proxy_failed"   // proxy container could not be updated
+	EventReconnect     = "reconnect"      // docker event stream reconnected
+	EventAlertFiring   = "alert_firing"   // beacon alert fired
+	EventAlertResolved = "alert_resolved" // beacon alert resolved
+
+	TypeWebhook = "webhook"
+	TypeSlack   = "slack"
+
+	EventHeader     = "X-Interlock-Event"
+	SignatureHeader = "X-Interlock-Signature"
+
+	queueSize       = 100
+	requestTimeout  = time.Second * 10
+	rateLimitWindow = time.Minute
+)
+
+// Event is an interlock event sent to the notifiers
+type Event struct {
+	Type    string            `json:"type"`
+	Ext     string            `json:"ext,omitempty"`
+	Time    time.Time         `json:"time"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Notifier delivers events to a webhook
+type Notifier struct {
+	cfg           *config.Notifier
+	client        *http.Client
+	events        map[string]bool
+	retryInterval time.Duration
+	queue         chan *Event
+	lock          *sync.Mutex
+	sent          []time.Time
+}
+
+// NewNotifier returns a notifier for the config and starts delivery
+func NewNotifier(cfg *config.Notifier) (*Notifier, error) {
+	switch cfg.Type {
+	case TypeWebhook, TypeSlack:
+	default:
+		return nil, fmt.Errorf("unknown notifier type: %s", cfg.Type)
+	}
+
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("notifier url must be specified: name=%s", cfg.Name)
+	}
+
+	retryInterval, err := time.ParseDuration(cfg.RetryInterval)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse notifier retry interval: %s", err)
+	}
+
+	events := map[string]bool{}
+	for _, e := range cfg.Events {
+		events[e] = true
+	}
+
+	n := &Notifier{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: requestTimeout,
+		},
+		events:        events,
+		retryInterval: retryInterval,
+		queue:         make(chan *Event, queueSize),
+		lock:          &sync.Mutex{},
+	}
+
+	go n.run()
+
+	return n, nil
+}
+
+// Notify queues the event for delivery.  Events are dropped if the rate
+// limit is reached or the queue is full.
+func (n *Notifier) Notify(e *Event) {
+	if len(n.events) > 0 && !n.events[e.Type] {
+		return
+	}
+
+	if !n.allow(time.Now()) {
+		log.Warnf("notifier rate limit reached; dropping event: name=%s type=%s", n.cfg.Name, e.Type)
+		return
+	}
+
+	select {
+	case n.queue <- e:
+	default:
+		log.Warnf("notifier queue full; dropping event: name=%s type=%s", n.cfg.Name, e.Type)
+	}
+}
+
+// allow returns true if the event is within the rate limit
+func (n *Notifier) allow(now time.Time) bool {
+	if n.cfg.RateLimit == 0 {
+		return true
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	sent := []time.Time{}
+	for _, t := range n.sent {
+		if now.Sub(t) < rateLimitWindow {
+			sent = append(sent, t)
+		}
+	}
+	n.sent = sent
+
+	if len(n.sent) >= n.cfg.RateLimit {
+		return false
+	}
+
+	n.sent = append(n.sent, now)
+
+	return true
+}
+
+func (n *Notifier) run() {
+	for e := range n.queue {
+		if err := n.deliver(e); err != nil {
+			log.Errorf("unable to deliver notification: name=%s type=%s err=%s", n.cfg.Name, e.Type, err)
+		}
+	}
+}
+
+// deliver sends the event and retries with backoff on failures
+func (n *Notifier) deliver(e *Event) error {
+	interval := n.retryInterval
+	for i := 0; ; i++ {
+		retry, err := n.send(e)
+		if err == nil {
+			log.Debugf("notification delivered: name=%s type=%s", n.cfg.Name, e.Type)
+			return nil
+		}
+
+		if !retry || i >= n.cfg.MaxRetries {
+			return err
+		}
+
+		log.Debugf("notification failed; retrying: name=%s type=%s interval=%s err=%s", n.cfg.Name, e.Type, interval, err)
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+// send posts the event to the webhook.  It returns true if the delivery
+// should be retried.
+func (n *Notifier) send(e *Event) (bool, error) {
+	body, err := payload(n.cfg.Type, e)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest("POST", n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, e.Type)
+	if n.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign([]byte(n.cfg.Secret), body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+}
+
+// Sign returns the hmac-sha256 signature of the body sent in the
+// X-Interlock-Signature header (sha256=<hex>)
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notifiers sends events to all configured notifiers
+type Notifiers []*Notifier
+
+// New returns the notifiers for the config
+func New(cfgs []*config.Notifier) (Notifiers, error) {
+	notifiers := Notifiers{}
+	for _, cfg := range cfgs {
+		n, err := NewNotifier(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Infof("notifier enabled: name=%s type=%s", cfg.Name, cfg.Type)
+		notifiers = append(notifiers, n)
+	}
+
+	return notifiers, nil
+}
+
+// Notify sends the event to all notifiers
+func (n Notifiers) Notify(e *Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	for _, x := range n {
+		x.Notify(e)
+	}
+}
@@ -0,0 +1,230 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ehazlett/interlock/config"
+)
+
+type testRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+// testServer returns a webhook server that responds with the statuses in
+// order (200 once exhausted) and sends the requests on the chan
+func testServer(t *testing.T, statuses ...int) (*httptest.Server, chan *testRequest) {
+	requests := make(chan *testRequest, 10)
+	lock := &sync.Mutex{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		lock.Lock()
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		lock.Unlock()
+
+		w.WriteHeader(status)
+		requests <- &testRequest{
+			Header: r.Header,
+			Body:   body,
+		}
+	}))
+
+	return srv, requests
+}
+
+func testNotifier(t *testing.T, cfg *config.Notifier) *Notifier {
+	config.SetNotifierDefaults(cfg)
+	cfg.RetryInterval = "10ms"
+
+	n, err := NewNotifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func receive(t *testing.T, requests chan *testRequest) *testRequest {
+	select {
+	case r := <-requests:
+		return r
+	case <-time.After(time.Second * 5):
+		t.Fatal("timeout waiting for notification")
+	}
+
+	return nil
+}
+
+func TestNotifyWebhook(t *testing.T) {
+	srv, requests := testServer(t)
+	defer srv.Close()
+
+	n := testNotifier(t, &config.Notifier{
+		URL:    srv.URL,
+		Secret: "s3cr3t",
+	})
+
+	Notifiers{n}.Notify(&Event{
+		Type:    EventReload,
+		Ext:     "nginx",
+		Message: "proxies reloaded",
+		Fields: map[string]string{
+			"trigger": "start",
+		},
+	})
+
+	r := receive(t, requests)
+
+	if h := r.Header.Get(EventHeader); h != EventReload {
+		t.Fatalf("expected event header %s; received %s", EventReload, h)
+	}
+
+	if sig := r.Header.Get(SignatureHeader); sig != Sign([]byte("s3cr3t"), r.Body) {
+		t.Fatalf("invalid signature: %s", sig)
+	}
+
+	var e *Event
+	if err := json.Unmarshal(r.Body, &e); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Type != EventReload || e.Ext != "nginx" || e.Fields["trigger"] != "start" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	if e.Time.IsZero() {
+		t.Fatal("expected event time to be set")
+	}
+}
+
+func TestNotifySlack(t *testing.T) {
+	srv, requests := testServer(t)
+	defer srv.Close()
+
+	n := testNotifier(t, &config.Notifier{
+		Type: TypeSlack,
+		URL:  srv.URL,
+	})
+
+	n.Notify(&Event{
+		Type:    EventReloadFailed,
+		Ext:     "haproxy",
+		Message: "reload failed",
+		Fields: map[string]string{
+			"error": "timeout",
+		},
+	})
+
+	r := receive(t, requests)
+
+	if sig := r.Header.Get(SignatureHeader); sig != "" {
+		t.Fatalf("expected no signature without a secret; received %s", sig)
+	}
+
+	var msg *slackMessage
+	if err := json.Unmarshal(r.Body, &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "*interlock (haproxy)* reload_failed: reload failed\nerror: `timeout`"
+	if msg.Text != expected {
+		t.Fatalf("expected text %q; received %q", expected, msg.Text)
+	}
+}
+
+func TestNotifyRetry(t *testing.T) {
+	srv, requests := testServer(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	n := testNotifier(t, &config.Notifier{
+		URL: srv.URL,
+	})
+
+	n.Notify(&Event{Type: EventReconnect})
+
+	// two failures and the successful retry
+	for i := 0; i < 3; i++ {
+		receive(t, requests)
+	}
+}
+
+func TestNotifyNoRetryClientError(t *testing.T) {
+	srv, _ := testServer(t, http.StatusBadRequest)
+	defer srv.Close()
+
+	n := testNotifier(t, &config.Notifier{
+		URL: srv.URL,
+	})
+
+	retry, err := n.send(&Event{Type: EventReload})
+	if err == nil {
+		t.Fatal("expected error for bad request")
+	}
+
+	if retry {
+		t.Fatal("expected no retry for bad request")
+	}
+}
+
+func TestNotifyEvents(t *testing.T) {
+	srv, requests := testServer(t)
+	defer srv.Close()
+
+	n := testNotifier(t, &config.Notifier{
+		URL:    srv.URL,
+		Events: []string{EventProxyFailed},
+	})
+
+	n.Notify(&Event{Type: EventReload})
+	n.Notify(&Event{Type: EventProxyFailed})
+
+	r := receive(t, requests)
+	if !strings.Contains(string(r.Body), EventProxyFailed) {
+		t.Fatalf("expected %s event; received %s", EventProxyFailed, r.Body)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	n := testNotifier(t, &config.Notifier{
+		URL:       "http://127.0.0.1:1",
+		RateLimit: 2,
+	})
+
+	now := time.Now()
+	if !n.allow(now) || !n.allow(now) {
+		t.Fatal("expected events within the rate limit")
+	}
+
+	if n.allow(now) {
+		t.Fatal("expected event over the rate limit to be dropped")
+	}
+
+	if !n.allow(now.Add(rateLimitWindow)) {
+		t.Fatal("expected event after the rate limit window")
+	}
+}
+
+func TestNewNotifierInvalid(t *testing.T) {
+	if _, err := NewNotifier(&config.Notifier{Type: "email", URL: "http://127.0.0.1", RetryInterval: "1s"}); err == nil {
+		t.Fatal("expected error for unknown type")
+	}
+
+	if _, err := NewNotifier(&config.Notifier{Type: TypeWebhook, RetryInterval: "1s"}); err == nil {
+		t.Fatal("expected error for missing url")
+	}
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// slackMessage is a slack incoming webhook message
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// payload returns the request body for the notifier type
+func payload(notifierType string, e *Event) ([]byte, error) {
+	switch notifierType {
+	case TypeSlack:
+		return json.Marshal(&slackMessage{
+			Text: slackText(e),
+		})
+	default:
+		return json.Marshal(e)
+	}
+}
+
+func slackText(e *Event) string {
+	title := "*interlock*"
+	if e.Ext != "" {
+		title = fmt.Sprintf("*interlock (%s)*", e.Ext)
+	}
+
+	lines := []string{
+		fmt.Sprintf("%s %s: %s", title, e.Type, e.Message),
+	}
+
+	keys := []string{}
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := e.Fields[k]
+		if strings.Contains(v, "\n") {
+			lines = append(lines, fmt.Sprintf("%s:\n```\n%s\n```", k, v))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("%s: `%s`", k, v))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/ehazlett/interlock/ext"
+)
+
+const (
+	apiMaintenancePath = "/api/maintenance"
+	apiReloadsPath     = "/api/reloads"
+)
+
+type maintenanceResponse struct {
+	Domains []string `json:"domains"`
+}
+
+type reloadsResponse struct {
+	Reloads []*ext.Reload `json:"reloads"`
+}
+
+// registerAPI adds the management api handlers to the mux
+func (s *Server) registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc(apiMaintenancePath, s.apiMaintenance)
+	mux.HandleFunc(apiMaintenancePath+"/", s.apiMaintenance)
+	mux.HandleFunc(apiReloadsPath, s.apiReloads)
+}
+
+// apiMaintenance lists the domains in maintenance mode and puts domains
+// into (PUT) or out of (DELETE) maintenance mode for /api/maintenance/<domain>
+func (s *Server) apiMaintenance(w http.ResponseWriter, r *http.Request) {
+	domain := strings.Trim(strings.TrimPrefix(r.URL.Path, apiMaintenancePath), "/")
+
+	switch r.Method {
+	case "GET":
+	case "PUT", "DELETE":
+		if domain == "" {
+			http.Error(w, "domain must be specified", http.StatusBadRequest)
+			return
+		}
+
+		enabled := r.Method == "PUT"
+		log.Infof("maintenance mode: domain=%s enabled=%v", domain, enabled)
+
+		for _, x := range s.maintenanceExtensions() {
+			x.SetMaintenance(domain, enabled)
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	seen := map[string]bool{}
+	resp := &maintenanceResponse{
+		Domains: []string{},
+	}
+
+	for _, x := range s.maintenanceExtensions() {
+		for _, d := range x.MaintenanceDomains() {
+			if !seen[d] {
+				seen[d] = true
+				resp.Domains = append(resp.Domains, d)
+			}
+		}
+	}
+
+	sort.Strings(resp.Domains)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Errorf("error encoding maintenance response: %s", err)
+	}
+}
+
+func (s *Server) maintenanceExtensions() []ext.MaintenanceExtension {
+	extensions := []ext.MaintenanceExtension{}
+	for _, x := range s.extensions {
+		if m, ok := x.(ext.MaintenanceExtension); ok {
+			extensions = append(extensions, m)
+		}
+	}
+
+	return extensions
+}
+
+// apiReloads lists the most recent proxy reloads of the extensions
+func (s *Server) apiReloads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := &reloadsResponse{
+		Reloads: []*ext.Reload{},
+	}
+
+	for _, x := range s.extensions {
+		if rx, ok := x.(ext.ReloadExtension); ok {
+			resp.Reloads = append(resp.Reloads, rx.Reloads()...)
+		}
+	}
+
+	sort.Sort(byStarted(resp.Reloads))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Errorf("error encoding reloads response: %s", err)
+	}
+}
+
+type byStarted []*ext.Reload
+
+func (b byStarted) Len() int           { return len(b) }
+func (b byStarted) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byStarted) Less(i, j int) bool { return b[i].Started.Before(b[j].Started) }
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	etypes "github.com/docker/engine-api/types/events"
+	"github.com/ehazlett/interlock/ext"
+	"github.com/ehazlett/interlock/ext/lb/utils"
+)
+
+type testMaintenanceExtension struct {
+	maintenance *utils.Maintenance
+}
+
+func (e *testMaintenanceExtension) Name() string {
+	return "test"
+}
+
+func (e *testMaintenanceExtension) HandleEvent(event *etypes.Message) error {
+	return nil
+}
+
+func (e *testMaintenanceExtension) MaintenanceDomains() []string {
+	return e.maintenance.Domains()
+}
+
+func (e *testMaintenanceExtension) SetMaintenance(domain string, enabled bool) {
+	e.maintenance.Set(domain, enabled)
+}
+
+func testAPIRequest(t *testing.T, s *Server, method, path string) *maintenanceResponse {
+	mux := http.NewServeMux()
+	s.registerAPI(mux)
+
+	req, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; received %d", http.StatusOK, w.Code)
+	}
+
+	var resp *maintenanceResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp
+}
+
+func TestAPIMaintenance(t *testing.T) {
+	x := &testMaintenanceExtension{
+		maintenance: utils.NewMaintenance(),
+	}
+	s := &Server{
+		extensions: []ext.Extension{x},
+	}
+
+	resp := testAPIRequest(t, s, "PUT", "/api/maintenance/example.com")
+	if len(resp.Domains) != 1 || resp.Domains[0] != "example.com" {
+		t.Fatalf("expected example.com in maintenance; received %v", resp.Domains)
+	}
+
+	if !x.maintenance.Enabled("example.com") {
+		t.Fatal("expected maintenance to be enabled")
+	}
+
+	resp = testAPIRequest(t, s, "DELETE", "/api/maintenance/example.com")
+	if len(resp.Domains) != 0 {
+		t.Fatalf("expected no domains in maintenance; received %v", resp.Domains)
+	}
+
+	resp = testAPIRequest(t, s, "GET", "/api/maintenance")
+	if len(resp.Domains) != 0 {
+		t.Fatalf("expected no domains in maintenance; received %v", resp.Domains)
+	}
+}
+
+type testReloadExtension struct {
+	testMaintenanceExtension
+	reloads []*ext.Reload
+}
+
+func (e *testReloadExtension) Reloads() []*ext.Reload {
+	return e.reloads
+}
+
+func (e *testReloadExtension) SetReloadHandler(fn func(r *ext.Reload)) {}
+
+func TestAPIReloads(t *testing.T) {
+	now := time.Now()
+	s := &Server{
+		extensions: []ext.Extension{
+			&testReloadExtension{
+				reloads: []*ext.Reload{
+					{Ext: "nginx", Trigger: "start", Started: now, Result: "ok"},
+				},
+			},
+			&testReloadExtension{
+				reloads: []*ext.Reload{
+					{Ext: "haproxy", Trigger: "maintenance", Started: now.Add(-time.Second), Result: "error", Error: "failed"},
+				},
+			},
+		},
+	}
+
+	mux := http.NewServeMux()
+	s.registerAPI(mux)
+
+	req, err := http.NewRequest("GET", "/api/reloads", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d; received %d", http.StatusOK, w.Code)
+	}
+
+	var resp *reloadsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Reloads) != 2 {
+		t.Fatalf("expected 2 reloads; received %d", len(resp.Reloads))
+	}
+
+	if resp.Reloads[0].Ext != "haproxy" || resp.Reloads[0].Error != "failed" {
+		t.Fatalf("expected failed haproxy reload first; received %+v", resp.Reloads[0])
+	}
+
+	if resp.Reloads[1].Trigger != "start" {
+		t.Fatalf("expected start trigger; received %s", resp.Reloads[1].Trigger)
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"github.com/ehazlett/interlock/ext"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -48,3 +49,13 @@ func NewMetrics() *Metrics {
 		Uptime:             uptime,
 	}
 }
+
+// reloaded updates the metrics and notifies with the record of an
+// extension reload
+func (s *Server) reloaded(r *ext.Reload) {
+	if r.Result == "ok" {
+		s.metrics.LastReloadDuration.Set(float64(r.Duration.Nanoseconds()))
+	}
+
+	s.notifyReload(r)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ehazlett/interlock/ext"
+	"github.com/ehazlett/interlock/notify"
+)
+
+// notifyReload sends the reload result and the proxy containers that
+// could not be updated to the notifiers
+func (s *Server) notifyReload(r *ext.Reload) {
+	fields := map[string]string{
+		"reload_id": r.ID,
+		"trigger":   r.Trigger,
+		"summary":   r.Summary,
+		"duration":  r.Duration.String(),
+	}
+
+	if r.Diff != nil && !r.Diff.Empty() {
+		fields["diff"] = r.Diff.String()
+	}
+
+	if r.Result == "ok" {
+		s.notifiers.Notify(&notify.Event{
+			Type:    notify.EventReload,
+			Ext:     r.Ext,
+			Message: "proxies reloaded",
+			Fields:  fields,
+		})
+	} else {
+		fields["error"] = r.Error
+		s.notifiers.Notify(&notify.Event{
+			Type:    notify.EventReloadFailed,
+			Ext:     r.Ext,
+			Message: "proxy reload failed",
+			Fields:  fields,
+		})
+	}
+
+	ids := []string{}
+	for id := range r.FailedProxies {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		s.notifiers.Notify(&notify.Event{
+			Type:    notify.EventProxyFailed,
+			Ext:     r.Ext,
+			Message: fmt.Sprintf("unable to update proxy container %s", shortID(id)),
+			Fields: map[string]string{
+				"container": id,
+				"error":     r.FailedProxies[id],
+				"reload_id": r.ID,
+				"trigger":   r.Trigger,
+			},
+		})
+	}
+}
+
+// alerted sends the fired or resolved alert to the notifiers
+func (s *Server) alerted(a *ext.Alert) {
+	typ := notify.EventAlertFiring
+	if a.State == ext.AlertResolved {
+		typ = notify.EventAlertResolved
+	}
+
+	fields := map[string]string{
+		"alert":     a.Name,
+		"expr":      a.Expr,
+		"container": a.Container,
+		"value":     strconv.FormatFloat(a.Value, 'f', 2, 64),
+		"since":     a.Since.Format(time.RFC3339),
+	}
+
+	if a.Rule != "" {
+		fields["rule"] = a.Rule
+	}
+
+	s.notifiers.Notify(&notify.Event{
+		Type:    typ,
+		Ext:     a.Ext,
+		Time:    a.Time,
+		Message: fmt.Sprintf("alert %s %s for container %s", a.Name, a.State, a.ContainerName),
+		Fields:  fields,
+	})
+}
+
+// reconnected notifies that the event stream recovered
+func (s *Server) reconnected() {
+	s.notifiers.Notify(&notify.Event{
+		Type:    notify.EventReconnect,
+		Message: "event stream reconnected",
+		Fields: map[string]string{
+			"docker": s.cfg.DockerURL,
+		},
+	})
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+
+	return id
+}
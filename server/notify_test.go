@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ehazlett/interlock/config"
+	"github.com/ehazlett/interlock/ext"
+	"github.com/ehazlett/interlock/notify"
+)
+
+func TestNotifyReload(t *testing.T) {
+	events := make(chan *notify.Event, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e *notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		events <- e
+	}))
+	defer srv.Close()
+
+	cfg := &config.Notifier{
+		URL: srv.URL,
+	}
+	config.SetNotifierDefaults(cfg)
+
+	notifiers, err := notify.New([]*config.Notifier{cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		notifiers: notifiers,
+	}
+
+	s.notifyReload(&ext.Reload{
+		Ext:     "nginx",
+		Trigger: "start:0123456789ab",
+		Result:  "error",
+		Error:   "error updating proxy containers",
+		FailedProxies: map[string]string{
+			"0123456789abcdef": "reload: container not running",
+		},
+	})
+
+	expected := []string{notify.EventReloadFailed, notify.EventProxyFailed}
+	for _, typ := range expected {
+		select {
+		case e := <-events:
+			if e.Type != typ {
+				t.Fatalf("expected %s event; received %s", typ, e.Type)
+			}
+
+			if e.Ext != "nginx" {
+				t.Fatalf("expected nginx ext; received %s", e.Ext)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("timeout waiting for %s event", typ)
+		}
+	}
+}
+
+func TestNotifyAlert(t *testing.T) {
+	events := make(chan *notify.Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e *notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		events <- e
+	}))
+	defer srv.Close()
+
+	cfg := &config.Notifier{
+		URL: srv.URL,
+	}
+	config.SetNotifierDefaults(cfg)
+
+	notifiers, err := notify.New([]*config.Notifier{cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		notifiers: notifiers,
+	}
+
+	s.alerted(&ext.Alert{
+		Name:          "memory",
+		Ext:           "beacon",
+		State:         ext.AlertResolved,
+		Expr:          "memory_usage_percent > 90",
+		Container:     "0123456789ab",
+		ContainerName: "web",
+		Value:         42,
+		Time:          time.Now(),
+	})
+
+	select {
+	case e := <-events:
+		if e.Type != notify.EventAlertResolved || e.Fields["alert"] != "memory" || e.Fields["value"] != "42.00" {
+			t.Fatalf("unexpected event: %+v", e)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timeout waiting for alert event")
+	}
+}
@@ -21,6 +21,8 @@ import (
 	"github.com/ehazlett/interlock/ext"
 	"github.com/ehazlett/interlock/ext/beacon"
 	"github.com/ehazlett/interlock/ext/lb"
+	"github.com/ehazlett/interlock/notify"
+	"github.com/ehazlett/interlock/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/context"
 )
@@ -34,6 +36,7 @@ type Server struct {
 	client        *client.Client
 	extensions    []ext.Extension
 	metrics       *Metrics
+	notifiers     notify.Notifiers
 	containerHash string
 }
 
@@ -58,6 +61,23 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		return nil, err
 	}
 
+	notifiers, err := notify.New(cfg.Notifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifiers = notifiers
+
+	if cfg.TraceExporter != "" {
+		e, err := tracing.NewExporter(cfg.TraceExporter, cfg.TraceEndpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Infof("tracing enabled: exporter=%s endpoint=%s", cfg.TraceExporter, cfg.TraceEndpoint)
+		tracing.SetExporter(e)
+	}
+
 	// channel setup
 	errChan = make(chan error)
 	eventErrChan = make(chan error)
@@ -75,6 +95,7 @@ func NewServer(cfg *config.Config) (*Server, error) {
 			log.Error("event stream fail; attempting to reconnect")
 
 			s.waitForSwarm()
+			s.reconnected()
 
 			restartChan <- true
 		}
@@ -93,6 +114,7 @@ func NewServer(cfg *config.Config) (*Server, error) {
 				log.Debug("swarm error detected")
 
 				s.waitForSwarm()
+				s.reconnected()
 
 				restartChan <- true
 			}
@@ -164,15 +186,23 @@ func NewServer(cfg *config.Config) (*Server, error) {
 				continue
 			}
 
+			ctx, span := tracing.StartSpan(context.Background(), "event")
+			span.SetAttribute("status", e.Status)
+			span.SetAttribute("type", e.Type)
+			span.SetAttribute("action", e.Action)
+			span.SetAttribute("container_id", e.ID)
+
 			// send the raw event for extension handling
-			for _, ext := range s.extensions {
-				log.Debugf("notifying extension: %s", ext.Name())
-				if err := ext.HandleEvent(e); err != nil {
+			for _, x := range s.extensions {
+				log.Debugf("notifying extension: %s", x.Name())
+				if err := s.handleEvent(ctx, x, e); err != nil {
 					errChan <- err
 					continue
 				}
 			}
 
+			span.Finish()
+
 			// counter
 			s.metrics.EventsProcessed.Inc()
 		}
@@ -192,6 +222,23 @@ func NewServer(cfg *config.Config) (*Server, error) {
 	return s, nil
 }
 
+// handleEvent sends the event to the extension in a span
+func (s *Server) handleEvent(ctx context.Context, x ext.Extension, e *etypes.Message) error {
+	ctx, span := tracing.StartSpan(ctx, "handle_event")
+	span.SetAttribute("ext", x.Name())
+	defer span.Finish()
+
+	var err error
+	if tx, ok := x.(ext.TracedExtension); ok {
+		err = tx.HandleEventContext(ctx, e)
+	} else {
+		err = x.HandleEvent(e)
+	}
+	span.SetError(err)
+
+	return err
+}
+
 func (s *Server) waitForSwarm() {
 	log.Info("waiting for event stream to become ready")
 
@@ -218,6 +265,7 @@ func (s *Server) loadExtensions(client *client.Client) {
 				log.Errorf("error loading load balancer extension: %s", err)
 				continue
 			}
+			p.SetReloadHandler(s.reloaded)
 			s.extensions = append(s.extensions, p)
 		case "beacon":
 			if !s.cfg.EnableMetrics {
@@ -229,6 +277,7 @@ func (s *Server) loadExtensions(client *client.Client) {
 				log.Errorf("error loading beacon extension: %s", err)
 				continue
 			}
+			p.SetAlertHandler(s.alerted)
 			s.extensions = append(s.extensions, p)
 		default:
 			log.Errorf("unsupported extension: name=%s", x.Name)
@@ -299,6 +348,11 @@ func (s *Server) Run() error {
 		http.Handle("/metrics", prometheus.Handler())
 	}
 
+	if s.cfg.EnableAPI {
+		// management api
+		s.registerAPI(http.DefaultServeMux)
+	}
+
 	if s.cfg.PollInterval != "" {
 		// run background poller
 		d, err := time.ParseDuration(s.cfg.PollInterval)
@@ -0,0 +1,292 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+
+	serviceName   = "interlock"
+	batchSize     = 100
+	batchInterval = time.Second * 5
+	queueSize     = 1000
+	exportTimeout = time.Second * 10
+
+	// otlp span kind and status codes
+	spanKindInternal = 1
+	statusCodeOK     = 1
+	statusCodeError  = 2
+)
+
+// BatchExporter batches the finished spans and writes them as OTLP JSON
+// (ExportTraceServiceRequest) to the sink
+type BatchExporter struct {
+	sink     func(data []byte) error
+	queue    chan *Span
+	flushReq chan chan bool
+}
+
+// NewExporter returns an exporter that posts the spans to the OTLP/HTTP
+// endpoint (otlp) or appends them as JSON lines to the file at endpoint
+// (file)
+func NewExporter(exporterType, endpoint string) (*BatchExporter, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("trace endpoint must be specified for the %s exporter", exporterType)
+	}
+
+	switch exporterType {
+	case ExporterOTLP:
+		return newBatchExporter(otlpSink(endpoint)), nil
+	case ExporterFile:
+		f, err := os.OpenFile(endpoint, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open trace file: %s", err)
+		}
+
+		return newBatchExporter(fileSink(f)), nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", exporterType)
+	}
+}
+
+func newBatchExporter(sink func(data []byte) error) *BatchExporter {
+	e := &BatchExporter{
+		sink:     sink,
+		queue:    make(chan *Span, queueSize),
+		flushReq: make(chan chan bool),
+	}
+
+	go e.run()
+
+	return e
+}
+
+// Export queues the span for export.  Spans are dropped if the queue is
+// full.
+func (e *BatchExporter) Export(s *Span) {
+	select {
+	case e.queue <- s:
+	default:
+		log.Warnf("trace queue full; dropping span: name=%s", s.Name)
+	}
+}
+
+// Flush exports the queued spans
+func (e *BatchExporter) Flush() {
+	done := make(chan bool)
+	e.flushReq <- done
+	<-done
+}
+
+func (e *BatchExporter) run() {
+	t := time.NewTicker(batchInterval)
+	defer t.Stop()
+
+	batch := []*Span{}
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := e.export(batch); err != nil {
+			log.Warnf("unable to export spans: count=%d err=%s", len(batch), err)
+		}
+
+		batch = []*Span{}
+	}
+
+	for {
+		select {
+		case s := <-e.queue:
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				export()
+			}
+		case <-t.C:
+			export()
+		case done := <-e.flushReq:
+			// drain the spans queued before the flush
+			for n := len(e.queue); n > 0; n-- {
+				batch = append(batch, <-e.queue)
+			}
+			export()
+			done <- true
+		}
+	}
+}
+
+func (e *BatchExporter) export(spans []*Span) error {
+	data, err := json.Marshal(otlpRequest(spans))
+	if err != nil {
+		return err
+	}
+
+	return e.sink(data)
+}
+
+func otlpSink(endpoint string) func(data []byte) error {
+	client := &http.Client{
+		Timeout: exportTimeout,
+	}
+
+	return func(data []byte) error {
+		resp, err := client.Post(endpoint, "application/json", bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+		}
+
+		return nil
+	}
+}
+
+func fileSink(w io.Writer) func(data []byte) error {
+	lock := &sync.Mutex{}
+
+	return func(data []byte) error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		_, err := w.Write(append(data, '\n'))
+		return err
+	}
+}
+
+// OTLP JSON encoding (opentelemetry/proto/collector/trace/v1)
+
+type otlpTraceRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   *otlpResource     `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []*otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope *otlpScope  `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string           `json:"traceId"`
+	SpanID            string           `json:"spanId"`
+	ParentSpanID      string           `json:"parentSpanId,omitempty"`
+	Name              string           `json:"name"`
+	Kind              int              `json:"kind"`
+	StartTimeUnixNano string           `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string           `json:"endTimeUnixNano"`
+	Attributes        []*otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string     `json:"key"`
+	Value *otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func otlpRequest(spans []*Span) *otlpTraceRequest {
+	otlpSpans := []*otlpSpan{}
+	for _, s := range spans {
+		otlpSpans = append(otlpSpans, toOTLPSpan(s))
+	}
+
+	return &otlpTraceRequest{
+		ResourceSpans: []*otlpResourceSpans{
+			{
+				Resource: &otlpResource{
+					Attributes: []*otlpAttribute{
+						stringAttribute("service.name", serviceName),
+					},
+				},
+				ScopeSpans: []*otlpScopeSpans{
+					{
+						Scope: &otlpScope{
+							Name: serviceName,
+						},
+						Spans: otlpSpans,
+					},
+				},
+			},
+		},
+	}
+}
+
+func toOTLPSpan(s *Span) *otlpSpan {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	keys := []string{}
+	for k := range s.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := []*otlpAttribute{}
+	for _, k := range keys {
+		attrs = append(attrs, stringAttribute(k, s.Attributes[k]))
+	}
+
+	status := &otlpStatus{
+		Code: statusCodeOK,
+	}
+	if s.Error != "" {
+		status.Code = statusCodeError
+		status.Message = s.Error
+	}
+
+	return &otlpSpan{
+		TraceID:           s.TraceID,
+		SpanID:            s.SpanID,
+		ParentSpanID:      s.ParentID,
+		Name:              s.Name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		Attributes:        attrs,
+		Status:            status,
+	}
+}
+
+func stringAttribute(key, value string) *otlpAttribute {
+	return &otlpAttribute{
+		Key: key,
+		Value: &otlpValue{
+			StringValue: value,
+		},
+	}
+}
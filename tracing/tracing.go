@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type spanKey struct{}
+
+var (
+	exporterLock = &sync.Mutex{}
+	exporter     Exporter
+)
+
+// Exporter receives the finished spans
+type Exporter interface {
+	Export(s *Span)
+}
+
+// SetExporter sets the exporter for the finished spans.  Spans are
+// discarded if no exporter is set.
+func SetExporter(e Exporter) {
+	exporterLock.Lock()
+	defer exporterLock.Unlock()
+
+	exporter = e
+}
+
+func currentExporter() Exporter {
+	exporterLock.Lock()
+	defer exporterLock.Unlock()
+
+	return exporter
+}
+
+// Span is a timed stage of the event pipeline
+type Span struct {
+	TraceID    string
+	SpanID     string
+	ParentID   string
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Error      string
+
+	lock  *sync.Mutex
+	ended bool
+}
+
+// StartSpan starts a span that is a child of the span in the context (or
+// a new trace) and returns the context with the span
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return StartSpanAt(ctx, name, time.Now())
+}
+
+// StartSpanAt starts a span at the start time (i.e. for a stage that is
+// only known once it finished)
+func StartSpanAt(ctx context.Context, name string, start time.Time) (context.Context, *Span) {
+	s := &Span{
+		SpanID:     newID(8),
+		Name:       name,
+		Start:      start,
+		Attributes: map[string]string{},
+		lock:       &sync.Mutex{},
+	}
+
+	if parent := FromContext(ctx); parent != nil {
+		s.TraceID = parent.TraceID
+		s.ParentID = parent.SpanID
+	} else {
+		s.TraceID = newID(16)
+	}
+
+	return ContextWithSpan(ctx, s), s
+}
+
+// ContextWithSpan returns the context with the span as the parent for
+// new spans
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// FromContext returns the span in the context or nil
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+
+	s, _ := ctx.Value(spanKey{}).(*Span)
+
+	return s
+}
+
+// SetAttribute sets an attribute on the span
+func (s *Span) SetAttribute(key, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.Attributes[key] = value
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.Error = err.Error()
+}
+
+// Finish ends the span and sends it to the exporter
+func (s *Span) Finish() {
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.lock.Unlock()
+
+	if e := currentExporter(); e != nil {
+		e.Export(s)
+	}
+}
+
+// Duration returns the duration of the finished span
+func (s *Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// ids only need to be unique within a trace
+		t := time.Now().UnixNano()
+		for i := range b {
+			b[i] = byte(t >> uint(8*(i%8)))
+		}
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,178 @@
+package tracing
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type testExporter struct {
+	spans []*Span
+}
+
+func (e *testExporter) Export(s *Span) {
+	e.spans = append(e.spans, s)
+}
+
+func TestStartSpan(t *testing.T) {
+	e := &testExporter{}
+	SetExporter(e)
+	defer SetExporter(nil)
+
+	ctx, root := StartSpan(context.Background(), "event")
+	_, child := StartSpan(ctx, "handle_event")
+	child.SetAttribute("container_id", "abc")
+	child.SetError(errors.New("inspect failed"))
+	child.Finish()
+	root.Finish()
+	// finished spans are only exported once
+	root.Finish()
+
+	if len(e.spans) != 2 {
+		t.Fatalf("expected 2 spans; received %d", len(e.spans))
+	}
+
+	if len(root.TraceID) != 32 || len(root.SpanID) != 16 {
+		t.Fatalf("invalid ids: trace=%s span=%s", root.TraceID, root.SpanID)
+	}
+
+	if root.ParentID != "" {
+		t.Fatalf("expected root span without parent; received %s", root.ParentID)
+	}
+
+	if child.TraceID != root.TraceID || child.ParentID != root.SpanID {
+		t.Fatalf("expected child of root span; received trace=%s parent=%s", child.TraceID, child.ParentID)
+	}
+
+	if child.Attributes["container_id"] != "abc" || child.Error != "inspect failed" {
+		t.Fatalf("unexpected child span: %+v", child)
+	}
+}
+
+func TestStartSpanAt(t *testing.T) {
+	start := time.Now().Add(-time.Second * 2)
+	_, s := StartSpanAt(context.Background(), "debounce", start)
+	s.Finish()
+
+	if s.Duration() < time.Second*2 {
+		t.Fatalf("expected duration of at least 2s; received %s", s.Duration())
+	}
+}
+
+func TestOTLPRequest(t *testing.T) {
+	_, s := StartSpan(context.Background(), "reload")
+	s.SetAttribute("ext", "nginx")
+	s.SetError(errors.New("reload failed"))
+	s.Finish()
+
+	req := otlpRequest([]*Span{s})
+
+	rs := req.ResourceSpans[0]
+	if attr := rs.Resource.Attributes[0]; attr.Key != "service.name" || attr.Value.StringValue != serviceName {
+		t.Fatalf("unexpected resource attribute: %+v", attr)
+	}
+
+	span := rs.ScopeSpans[0].Spans[0]
+	if span.TraceID != s.TraceID || span.SpanID != s.SpanID || span.Name != "reload" {
+		t.Fatalf("unexpected span: %+v", span)
+	}
+
+	if span.Status.Code != statusCodeError || span.Status.Message != "reload failed" {
+		t.Fatalf("expected error status; received %+v", span.Status)
+	}
+
+	if len(span.Attributes) != 1 || span.Attributes[0].Key != "ext" {
+		t.Fatalf("unexpected attributes: %+v", span.Attributes)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	requests := make(chan *otlpTraceRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req *otlpTraceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		requests <- req
+	}))
+	defer srv.Close()
+
+	e, err := NewExporter(ExporterOTLP, srv.URL+"/v1/traces")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, s := StartSpan(context.Background(), "generate")
+	s.Finish()
+	e.Export(s)
+	e.Flush()
+
+	req := <-requests
+	if n := len(req.ResourceSpans[0].ScopeSpans[0].Spans); n != 1 {
+		t.Fatalf("expected 1 span; received %d", n)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "interlock-trace-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "traces.json")
+	e, err := NewExporter(ExporterFile, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"copy", "reload"} {
+		_, s := StartSpan(context.Background(), name)
+		s.Finish()
+		e.Export(s)
+	}
+	e.Flush()
+
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var req *otlpTraceRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			t.Fatal(err)
+		}
+
+		if n := len(req.ResourceSpans[0].ScopeSpans[0].Spans); n != 2 {
+			t.Fatalf("expected 2 spans in the batch; received %d", n)
+		}
+		lines++
+	}
+
+	if lines != 1 {
+		t.Fatalf("expected 1 batch; received %d", lines)
+	}
+}
+
+func TestNewExporterInvalid(t *testing.T) {
+	if _, err := NewExporter("zipkin", "http://127.0.0.1"); err == nil {
+		t.Fatal("expected error for unknown exporter")
+	}
+
+	if _, err := NewExporter(ExporterOTLP, ""); err == nil {
+		t.Fatal("expected error for missing endpoint")
+	}
+}